@@ -17,22 +17,51 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/SENERGY-Platform/api-aggregator/pkg"
 	"github.com/SENERGY-Platform/api-aggregator/pkg/api"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/logging"
 	"log"
+	"os"
 )
 
 func main() {
 	defer fmt.Println("exit application")
 	configLocation := flag.String("config", "config.json", "configuration file")
+	record := flag.String("record", "", "record all upstream exchanges to this file (overwrites record_upstreams)")
+	replay := flag.String("replay", "", "demo mode: answer upstream requests from this recording (overwrites replay_upstreams)")
 	flag.Parse()
 
 	config, err := pkg.LoadConfig(*configLocation)
 	if err != nil {
 		log.Fatal("unable to load config", err)
-	} else {
-		api.Start(pkg.New(config))
 	}
+	if *record != "" {
+		config.RecordUpstreams = *record
+	}
+	if *replay != "" {
+		config.ReplayUpstreams = *replay
+	}
+	err = logging.Setup(os.Stdout, config.LogLevel, config.LogFormat)
+	if err != nil {
+		log.Fatal("unable to setup logging", err)
+	}
+	shutdownTracing, err := pkg.SetupTracing(context.Background(), config)
+	if err != nil {
+		log.Fatal("unable to setup tracing", err)
+	}
+	defer shutdownTracing(context.Background())
+	config, middleware, shutdownRecording, err := config.UpstreamMiddleware()
+	if err != nil {
+		log.Fatal("unable to setup upstream recording", err)
+	}
+	defer shutdownRecording()
+	lib := pkg.NewWithMiddleware(config, middleware)
+	err = lib.StartCacheInvalidation(context.Background(), config.CacheInvalidationBroker())
+	if err != nil {
+		log.Fatal("unable to start cache invalidation", err)
+	}
+	api.Start(lib)
 }
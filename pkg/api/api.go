@@ -17,10 +17,12 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/SENERGY-Platform/service-commons/pkg/accesslog"
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
@@ -29,19 +31,31 @@ import (
 	"github.com/SENERGY-Platform/api-aggregator/pkg/api/util"
 	"github.com/SENERGY-Platform/api-aggregator/pkg/auth"
 	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/upstream"
 	"github.com/julienschmidt/httprouter"
 )
 
+// WarningsHeader lists the warnings of partial responses
+const WarningsHeader = "X-Aggregator-Warnings"
+
+// defaultLogDuration is the log duration of GET /devices/:id and GET /hubs/:id if no log query parameter is given
+const defaultLogDuration = "24h"
+
 func Start(lib pkg.Interface) {
-	log.Println("start server on port: ", lib.Config().ServerPort)
-	httpHandler := getRoutes(lib)
-	corseHandler := util.NewCors(httpHandler)
-	logger := accesslog.New(corseHandler)
-	log.Println(http.ListenAndServe(":"+lib.Config().ServerPort, logger))
+	slog.Info("start server", "port", lib.Config().ServerPort)
+	slog.Error("server stopped", "error", http.ListenAndServe(":"+lib.Config().ServerPort, NewHandler(lib)))
 }
 
-func getRoutes(lib pkg.Interface) (router *httprouter.Router) {
-	router = httprouter.New()
+// NewHandler returns the routes of the aggregator wrapped in the metrics, tracing, cors and logging middlewares
+func NewHandler(lib pkg.Interface) http.Handler {
+	httpHandler := util.NewMetrics(getRoutes(lib), lib.Metrics())
+	tracingHandler := util.NewTracing(httpHandler)
+	corseHandler := util.NewCors(tracingHandler)
+	return util.NewLogging(accesslog.NewWithLogger(corseHandler, slog.Default()))
+}
+
+func getRoutes(lib pkg.Interface) (router *util.Router) {
+	router = util.NewRouter()
 
 	//returns device-classes used by user devices
 	router.GET("/device-class-uses", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
@@ -50,9 +64,9 @@ func getRoutes(lib pkg.Interface) (router *httprouter.Router) {
 			http.Error(writer, err.Error(), http.StatusBadRequest)
 			return
 		}
-		result, err := lib.GetDeviceClassUses(token)
+		result, err := lib.GetDeviceClassUses(request.Context(), token)
 		if err != nil {
-			log.Println("ERROR: ", err)
+			logError(request.Context(), err)
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -60,48 +74,149 @@ func getRoutes(lib pkg.Interface) (router *httprouter.Router) {
 		json.NewEncoder(writer).Encode(result)
 	})
 
+	/*
+		query-parameter:
+			optional:
+				limit 	{int} 		may default to 100
+				offset 	{int}		may default to 0
+				cursor	{string}	next_cursor or prev_cursor of a previous response (replaces offset)
+				log		{string}	influxdb duration (for example 4h) https://docs.influxdata.com/influxdb/v1.7/query_language/spec/#durations
+				search				{string}	name or display name contains
+				sort				{string}	<field>.<asc|desc> with field name, display_name or log_state; defaults to name.asc
+				device_type_ids		{string}	comma separated
+				device_class_ids	{string}	comma separated
+				attribute			{string}	<key>:<value>; may be repeated, all have to match
+				log_state			{string}	connected, disconnected or unknown
+				owner				{string}	owner id
+				fields				{string}	comma separated top level fields of the devices (sparse fieldset)
+				expand				{string}	comma separated list of device_type, device_class, hub, log_history, log_edge, log_start and dependent_processes;
+											if set, only the listed enrichments are added and log only sets the duration.
+											if not set, device_type is added and log adds log_history and log_edge.
+	*/
 	router.GET("/devices", func(res http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		limit := r.URL.Query().Get("limit")
-		offset := r.URL.Query().Get("offset")
-		logDuration := r.URL.Query().Get("log")
+		ctx := pkg.WithWarnings(r.Context())
+		page, err, code := listDevices(ctx, lib, r)
+		if err != nil {
+			handleError(ctx, res, err, code)
+			return
+		}
+		result, err := legacyDevices(page.Items, r.URL.Query().Get("log") != "" && !r.URL.Query().Has("expand"))
+		if err != nil {
+			handleError(ctx, res, err, http.StatusInternalServerError)
+			return
+		}
+		result = selectFields(result, parseFields(r))
+		setPageHeaders(res, r, page)
+		setWarningsHeader(res, ctx)
+		res.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(res).Encode(result)
+	})
 
-		limit, offset = limitOffsetDefault(limit, offset)
+	//same as /devices but returns a model.Page of model.AggregatedDevice values
+	router.GET("/v2/devices", func(res http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctx := pkg.WithWarnings(r.Context())
+		result, err, code := listDevices(ctx, lib, r)
+		if err != nil {
+			handleError(ctx, res, err, code)
+			return
+		}
+		setPageHeaders(res, r, result)
+		setWarningsHeader(res, ctx)
+		res.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if fields := parseFields(r); len(fields) > 0 {
+			selected, err := pageWithFields(result, fields)
+			if err != nil {
+				handleError(ctx, res, err, http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(res).Encode(selected)
+			return
+		}
+		json.NewEncoder(res).Encode(result)
+	})
 
-		intLimit, err := strconv.Atoi(limit)
+	//returns the devices of a model.DeviceLookup as model.DeviceLookupResult; ids that can not be read are listed in errors
+	router.POST("/devices/query", func(res http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctx := pkg.WithWarnings(r.Context())
+		result, err, code := lookupDevices(ctx, lib, r)
 		if err != nil {
-			log.Println("ERROR: ", err)
-			http.Error(res, "limit is not a number: "+err.Error(), http.StatusBadRequest)
+			handleError(ctx, res, err, code)
 			return
 		}
+		setWarningsHeader(res, ctx)
+		res.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(res).Encode(result)
+	})
 
-		intOffset, err := strconv.Atoi(offset)
+	/*
+		returns one model.AggregatedDevice; 404 or 403 if the device can not be read
+		query-parameter:
+			optional:
+				log		{string}	influxdb duration of log_history and log_edge; defaults to 24h
+				fields	{string}	comma separated top level fields of the device (sparse fieldset)
+				expand	{string}	comma separated list of device_type, device_class, hub, log_history, log_edge, log_start and dependent_processes;
+								defaults to all
+	*/
+	router.GET("/devices/:id", func(res http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctx := pkg.WithWarnings(r.Context())
+		result, err, code := getDevice(ctx, lib, r, ps.ByName("id"))
 		if err != nil {
-			log.Println("ERROR: ", err)
-			http.Error(res, "offset is not a number: "+err.Error(), http.StatusBadRequest)
+			handleError(ctx, res, err, code)
 			return
 		}
+		setWarningsHeader(res, ctx)
+		res.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if fields := parseFields(r); len(fields) > 0 {
+			selected, err := toMaps([]model.AggregatedDevice{result})
+			if err != nil {
+				handleError(ctx, res, err, http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(res).Encode(selectFields(selected, fields)[0])
+			return
+		}
+		json.NewEncoder(res).Encode(result)
+	})
 
+	//returns the process deployments (with their bpmn tasks) that depend on the device as list of model.Dependent
+	router.GET("/devices/:id/dependents", func(res http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctx := pkg.WithWarnings(r.Context())
 		token, err := auth.GetParsedToken(r)
 		if err != nil {
-			http.Error(res, err.Error(), http.StatusBadRequest)
+			handleError(ctx, res, err, http.StatusBadRequest)
 			return
 		}
+		id := ps.ByName("id")
+		result, err := lib.GetDependents(ctx, token, []string{id}, nil)
+		if err != nil {
+			handleError(ctx, res, err, http.StatusInternalServerError)
+			return
+		}
+		setWarningsHeader(res, ctx)
+		res.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(res).Encode(result.Devices[id])
+	})
 
-		result, err := lib.FindDevices(token, intLimit, intOffset)
+	//returns the dependents of the devices and event filters of a model.DependentsLookup as model.DependentsLookupResult
+	router.POST("/dependents/query", func(res http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctx := pkg.WithWarnings(r.Context())
+		token, err := auth.GetParsedToken(r)
 		if err != nil {
-			log.Println("ERROR: ", err)
-			http.Error(res, err.Error(), http.StatusInternalServerError)
+			handleError(ctx, res, err, http.StatusBadRequest)
 			return
 		}
-		if logDuration != "" {
-			result, err = lib.CompleteDeviceHistory(token, logDuration, result)
+		lookup := model.DependentsLookup{}
+		err = json.NewDecoder(r.Body).Decode(&lookup)
+		if err != nil {
+			handleError(ctx, res, err, http.StatusBadRequest)
+			return
 		}
-
+		result, err := lib.GetDependents(ctx, token, lookup.DeviceIds, lookup.EventIds)
 		if err != nil {
-			log.Println("ERROR: ", err)
-			http.Error(res, err.Error(), http.StatusInternalServerError)
+			handleError(ctx, res, err, http.StatusInternalServerError)
 			return
 		}
+		setWarningsHeader(res, ctx)
 		res.Header().Set("Content-Type", "application/json; charset=utf-8")
 		json.NewEncoder(res).Encode(result)
 	})
@@ -111,136 +226,135 @@ func getRoutes(lib pkg.Interface) (router *httprouter.Router) {
 			optional:
 				limit 	{int} 		may default to 100
 				offset 	{int}		may default to 0
+				cursor	{string}	next_cursor or prev_cursor of a previous response (replaces offset)
 				log		{string}	influxdb duration (for example 4h) https://docs.influxdata.com/influxdb/v1.7/query_language/spec/#durations
+				search		{string}	name contains
+				sort		{string}	<field>.<asc|desc> with field name or log_state; defaults to name.asc
+				log_state	{string}	connected, disconnected or unknown
+				device_id	{string}	hubs containing the device
+				min_devices	{int}		minimal count of devices
+				max_devices	{int}		maximal count of devices
 	*/
 	router.GET("/hubs", func(res http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		limit := r.URL.Query().Get("limit")
-		offset := r.URL.Query().Get("offset")
-		logDuration := r.URL.Query().Get("log")
-
-		limit, offset = limitOffsetDefault(limit, offset)
-
-		token, err := auth.GetParsedToken(r)
+		ctx := pkg.WithWarnings(r.Context())
+		page, err, code := listHubs(ctx, lib, r)
 		if err != nil {
-			http.Error(res, err.Error(), http.StatusBadRequest)
+			handleError(ctx, res, err, code)
 			return
 		}
-
-		result := []map[string]interface{}{}
-
-		if limit == "" && offset == "" {
-			result, err = lib.ListAllGateways(token)
-		} else {
-			intLimit, err := strconv.ParseInt(limit, 10, 64)
-			if err != nil {
-				err = fmt.Errorf("limit is not a number: %w", err)
-				http.Error(res, err.Error(), http.StatusBadRequest)
-				return
-			}
-
-			intOffset, err := strconv.ParseInt(offset, 10, 64)
-			if err != nil {
-				err = fmt.Errorf("offset is not a number: %w", err)
-				http.Error(res, err.Error(), http.StatusBadRequest)
-				return
-			}
-			result, err = lib.ListGateways(token, intLimit, intOffset)
-		}
+		result, err := legacyHubs(page.Items, r.URL.Query().Get("log") != "")
 		if err != nil {
-			log.Println("ERROR: ", err)
-			http.Error(res, err.Error(), http.StatusInternalServerError)
+			handleError(ctx, res, err, http.StatusInternalServerError)
 			return
 		}
+		setPageHeaders(res, r, page)
+		setWarningsHeader(res, ctx)
+		res.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(res).Encode(result)
+	})
 
-		if logDuration != "" {
-			result, err = lib.CompleteGatewayHistory(token, logDuration, result)
-		}
-
+	//same as /hubs but returns a model.Page of model.AggregatedHub values
+	router.GET("/v2/hubs", func(res http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctx := pkg.WithWarnings(r.Context())
+		result, err, code := listHubs(ctx, lib, r)
 		if err != nil {
-			log.Println("ERROR: ", err)
-			http.Error(res, err.Error(), http.StatusInternalServerError)
+			handleError(ctx, res, err, code)
 			return
 		}
-
+		setPageHeaders(res, r, result)
+		setWarningsHeader(res, ctx)
 		res.Header().Set("Content-Type", "application/json; charset=utf-8")
 		json.NewEncoder(res).Encode(result)
 	})
 
-	//reads query parameter like https://docs.camunda.org/manual/7.5/reference/rest/deployment/get-query/
-	router.GET("/processes", func(res http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		log.Println("DEBUG: ", r.URL.Query())
+	/*
+		returns one model.AggregatedHub with its devices; 404 or 403 if the hub can not be read
+		query-parameter:
+			optional:
+				log		{string}	influxdb duration of log_history and log_edge of the hub and its devices; defaults to 24h
+	*/
+	router.GET("/hubs/:id", func(res http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctx := pkg.WithWarnings(r.Context())
 		token, err := auth.GetParsedToken(r)
 		if err != nil {
-			http.Error(res, err.Error(), http.StatusBadRequest)
+			handleError(ctx, res, err, http.StatusBadRequest)
 			return
 		}
-		result, err := lib.GetExtendedProcessList(token, r.URL.Query())
+		logDuration := r.URL.Query().Get("log")
+		if logDuration == "" {
+			logDuration = defaultLogDuration
+		}
+		result, err, code := lib.GetHub(ctx, token, ps.ByName("id"), logDuration)
 		if err != nil {
-			log.Println("ERROR: ", err)
-			http.Error(res, err.Error(), http.StatusInternalServerError)
+			handleError(ctx, res, err, code)
 			return
 		}
+		setWarningsHeader(res, ctx)
 		res.Header().Set("Content-Type", "application/json; charset=utf-8")
 		json.NewEncoder(res).Encode(result)
 	})
 
-	router.GET("/aspects/:id/measuring-functions", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
-		token, err := auth.GetParsedToken(request)
+	//reads query parameter like https://docs.camunda.org/manual/7.5/reference/rest/deployment/get-query/
+	router.GET("/processes", func(res http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctx := pkg.WithWarnings(r.Context())
+		processes, err, code := listProcesses(ctx, lib, r)
 		if err != nil {
-			http.Error(writer, err.Error(), http.StatusBadRequest)
+			handleError(ctx, res, err, code)
 			return
 		}
-		// Get from semantic
-		id := params.ByName("id")
-		functions, err, code := lib.GetMeasuringFunctionsForAspect(token, id)
+		result, err := legacyProcesses(processes)
 		if err != nil {
-			log.Println("ERROR: ", err)
-			http.Error(writer, err.Error(), code)
+			handleError(ctx, res, err, http.StatusInternalServerError)
 			return
 		}
+		setWarningsHeader(res, ctx)
+		res.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(res).Encode(result)
+	})
 
-		// Get from Permsearch (import-types)
-		node, err := lib.GetAspectNodes([]string{id}, token)
+	//same as /processes but returns model.AggregatedProcess values
+	router.GET("/v2/processes", func(res http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctx := pkg.WithWarnings(r.Context())
+		result, err, code := listProcesses(ctx, lib, r)
 		if err != nil {
-			log.Println("ERROR: ", err)
-			http.Error(writer, err.Error(), http.StatusBadGateway)
+			handleError(ctx, res, err, code)
 			return
 		}
-		if len(node) != 1 {
-			log.Println("ERROR: ", err)
-			http.Error(writer, "unexpected length of reponse", http.StatusBadGateway)
+		setWarningsHeader(res, ctx)
+		res.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(res).Encode(result)
+	})
+
+	router.GET("/aspects/:id/measuring-functions", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		ctx := pkg.WithWarnings(request.Context())
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
 			return
 		}
-		ids := append(node[0].DescendentIds, node[0].Id)
-
-		importTypes, err, code := lib.GetImportTypesWithAspect(token, ids)
+		// Get from semantic
+		id := params.ByName("id")
+		functions, err, code := lib.GetMeasuringFunctionsForAspect(ctx, token, id)
 		if err != nil {
-			log.Println("ERROR: ", err)
+			logError(ctx, err)
 			http.Error(writer, err.Error(), code)
 			return
 		}
 
-		additionalFunctionIds := []string{}
-		for _, importType := range importTypes {
-			for _, c := range importType.Criteria {
-				if isInSlice(ids, c.AspectId) && !isInSlice(additionalFunctionIds, c.FunctionId) && !isFunctionLoaded(functions, c.FunctionId) {
-					additionalFunctionIds = append(additionalFunctionIds, c.FunctionId)
-				}
-			}
-		}
-		additionalFunctions, err, code := lib.GetMeasuringFunctions(token, additionalFunctionIds)
+		functions, err, code = addImportTypeFunctions(ctx, lib, token, id, functions)
 		if err != nil {
-			log.Println("ERROR: ", err)
+			logError(ctx, err)
 			http.Error(writer, err.Error(), code)
 			return
 		}
-		functions = append(functions, additionalFunctions...)
 
+		setWarningsHeader(writer, ctx)
 		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
 		json.NewEncoder(writer).Encode(functions)
 	})
 
 	router.GET("/aspect-nodes", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		ctx := pkg.WithWarnings(request.Context())
 		function := request.URL.Query().Get("function")
 		if function != "measuring-function" {
 			http.Error(writer, "May only use function=measuring-function", http.StatusBadRequest)
@@ -257,72 +371,462 @@ func getRoutes(lib pkg.Interface) (router *httprouter.Router) {
 		}
 
 		// Get for devices, ancestors already included
-		result, err = lib.GetAspectNodesWithMeasuringFunction(token)
-
-		aspectIds := []string{}
-		for _, r := range result {
-			aspectIds = append(aspectIds, r.Id)
+		result, err = lib.GetAspectNodesWithMeasuringFunction(ctx, token)
+		if err != nil {
+			logError(ctx, err)
+			http.Error(writer, err.Error(), http.StatusBadGateway)
+			return
 		}
 
-		// Get import types and prepare loading additional nodes
-		importTypes, err, code := lib.GetImportTypes(token)
+		result, err, code := addImportTypeAspectNodes(ctx, lib, token, result)
 		if err != nil {
+			logError(ctx, err)
 			http.Error(writer, err.Error(), code)
 			return
 		}
-		additionalAspectIds := []string{}
-		for _, t := range importTypes {
-			for _, c := range t.Criteria {
-				if !isInSlice(aspectIds, c.AspectId) {
-					additionalAspectIds = append(additionalAspectIds, c.AspectId)
-					aspectIds = append(aspectIds, c.AspectId)
-				}
+
+		setWarningsHeader(writer, ctx)
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			slog.WarnContext(ctx, "unable to encode response", "error", err)
+		}
+		return
+	})
+
+	//OpenAPI 3 document of all routes
+	router.GET("/openapi.json", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		writer.Write(OpenApiSpec)
+	})
+
+	//prometheus metrics of endpoints and upstream services
+	router.Handler(http.MethodGet, "/metrics", lib.Metrics().Handler())
+
+	//returns the state of the circuit breakers of all upstream services; admin only
+	router.GET("/admin/circuit-breakers", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !token.IsAdmin() {
+			http.Error(writer, "only admins may access circuit breaker states", http.StatusForbidden)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(lib.GetCircuitBreakerStatus())
+		if err != nil {
+			slog.WarnContext(request.Context(), "unable to encode response", "error", err)
+		}
+	})
+
+	//returns hit/miss stats of the caches of semantic metadata; admin only
+	router.GET("/admin/caches", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !token.IsAdmin() {
+			http.Error(writer, "only admins may access cache stats", http.StatusForbidden)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(lib.GetCacheStats())
+		if err != nil {
+			slog.WarnContext(request.Context(), "unable to encode response", "error", err)
+		}
+	})
+
+	return
+
+}
+
+// addImportTypeFunctions adds the measuring functions used by import types with the aspect (or one of its descendants)
+// on partial responses failing upstream calls return the unchanged functions list
+func addImportTypeFunctions(ctx context.Context, lib pkg.Interface, token auth.Token, aspectId string, functions []model.MeasuringFunction) ([]model.MeasuringFunction, error, int) {
+	// Get from Permsearch (import-types)
+	node, err := lib.GetAspectNodes(ctx, []string{aspectId}, token)
+	if err == nil && len(node) != 1 {
+		err = errors.New("unexpected length of reponse")
+	}
+	if err != nil {
+		if pkg.Degrade(ctx, lib.Config(), pkg.UpstreamIot, pkg.StepAspectNodes, err) {
+			return functions, nil, http.StatusOK
+		}
+		return nil, err, http.StatusBadGateway
+	}
+	ids := append(node[0].DescendentIds, node[0].Id)
+
+	importTypes, err, code := lib.GetImportTypesWithAspect(ctx, token, ids)
+	if err != nil {
+		if pkg.Degrade(ctx, lib.Config(), pkg.UpstreamImportRepo, pkg.StepImportTypes, err) {
+			return functions, nil, http.StatusOK
+		}
+		return nil, err, code
+	}
+
+	additionalFunctionIds := []string{}
+	for _, importType := range importTypes {
+		for _, c := range importType.Criteria {
+			if isInSlice(ids, c.AspectId) && !isInSlice(additionalFunctionIds, c.FunctionId) && !isFunctionLoaded(functions, c.FunctionId) {
+				additionalFunctionIds = append(additionalFunctionIds, c.FunctionId)
 			}
 		}
+	}
+	additionalFunctions, err, code := lib.GetMeasuringFunctions(ctx, token, additionalFunctionIds)
+	if err != nil {
+		if pkg.Degrade(ctx, lib.Config(), pkg.UpstreamIot, pkg.StepFunctions, err) {
+			return functions, nil, http.StatusOK
+		}
+		return nil, err, code
+	}
+	return append(functions, additionalFunctions...), nil, http.StatusOK
+}
 
-		// Get additional nodes if needed
-		if len(additionalAspectIds) > 0 {
-			importTypeNodes, err := lib.GetAspectNodes(additionalAspectIds, token)
-			if err != nil {
-				log.Println("ERROR: ", err)
-				http.Error(writer, err.Error(), http.StatusBadGateway)
-				return
+// addImportTypeAspectNodes adds the aspect nodes (and their ancestors) used by import types
+// on partial responses failing upstream calls return the unchanged node list
+func addImportTypeAspectNodes(ctx context.Context, lib pkg.Interface, token auth.Token, result []model.AspectNode) ([]model.AspectNode, error, int) {
+	aspectIds := []string{}
+	for _, r := range result {
+		aspectIds = append(aspectIds, r.Id)
+	}
+
+	// Get import types and prepare loading additional nodes
+	importTypes, err, code := lib.GetImportTypes(ctx, token)
+	if err != nil {
+		if pkg.Degrade(ctx, lib.Config(), pkg.UpstreamImportRepo, pkg.StepImportTypes, err) {
+			return result, nil, http.StatusOK
+		}
+		return nil, err, code
+	}
+	additionalAspectIds := []string{}
+	for _, t := range importTypes {
+		for _, c := range t.Criteria {
+			if !isInSlice(aspectIds, c.AspectId) {
+				additionalAspectIds = append(additionalAspectIds, c.AspectId)
+				aspectIds = append(aspectIds, c.AspectId)
 			}
-			result = append(result, importTypeNodes...)
-
-			// Check for ancestors of additional nodes and prepare loading those
-			additionalAspectIds = []string{}
-			for _, node := range importTypeNodes {
-				for _, ancestorId := range node.AncestorIds {
-					if !isInSlice(aspectIds, ancestorId) {
-						additionalAspectIds = append(additionalAspectIds, ancestorId)
-						aspectIds = append(aspectIds, ancestorId)
-					}
-				}
+		}
+	}
+
+	// Get additional nodes if needed
+	if len(additionalAspectIds) == 0 {
+		return result, nil, http.StatusOK
+	}
+	importTypeNodes, err := lib.GetAspectNodes(ctx, additionalAspectIds, token)
+	if err != nil {
+		if pkg.Degrade(ctx, lib.Config(), pkg.UpstreamIot, pkg.StepAspectNodes, err) {
+			return result, nil, http.StatusOK
+		}
+		return nil, err, http.StatusBadGateway
+	}
+
+	// Check for ancestors of additional nodes and prepare loading those
+	additionalAspectIds = []string{}
+	for _, node := range importTypeNodes {
+		for _, ancestorId := range node.AncestorIds {
+			if !isInSlice(aspectIds, ancestorId) {
+				additionalAspectIds = append(additionalAspectIds, ancestorId)
+				aspectIds = append(aspectIds, ancestorId)
 			}
+		}
+	}
 
-			// Load ancestors if needed
-			if len(additionalAspectIds) > 0 {
-				additionalNodes, err := lib.GetAspectNodes(additionalAspectIds, token)
-				if err != nil {
-					log.Println("ERROR: ", err)
-					http.Error(writer, err.Error(), http.StatusBadGateway)
-					return
-				}
-				result = append(result, additionalNodes...)
+	// Load ancestors if needed
+	if len(additionalAspectIds) > 0 {
+		additionalNodes, err := lib.GetAspectNodes(ctx, additionalAspectIds, token)
+		if err != nil {
+			if pkg.Degrade(ctx, lib.Config(), pkg.UpstreamIot, pkg.StepAspectNodes, err) {
+				return result, nil, http.StatusOK
 			}
+			return nil, err, http.StatusBadGateway
 		}
+		importTypeNodes = append(importTypeNodes, additionalNodes...)
+	}
+	return append(result, importTypeNodes...), nil, http.StatusOK
+}
 
-		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
-		err = json.NewEncoder(writer).Encode(result)
+// deviceOrder returns the order of lib.FindDevices for a validated pkg.DeviceQuery.SortBy
+func deviceOrder(sortBy string) order[model.AggregatedDevice] {
+	field, direction := getSortParts(sortBy)
+	result := order[model.AggregatedDevice]{
+		id:   func(device model.AggregatedDevice) string { return device.Id },
+		key:  func(device model.AggregatedDevice) string { return device.Name },
+		desc: direction == "desc",
+	}
+	switch field {
+	case "display_name":
+		result.key = func(device model.AggregatedDevice) string { return device.DisplayName }
+	case "log_state":
+		result.key = func(device model.AggregatedDevice) string { return string(device.ConnectionState) }
+		result.tied = true
+	}
+	return result
+}
+
+// hubOrder returns the order of lib.ListGateways for a validated pkg.HubQuery.SortBy
+func hubOrder(sortBy string) order[model.AggregatedHub] {
+	field, direction := getSortParts(sortBy)
+	result := order[model.AggregatedHub]{
+		id:   func(hub model.AggregatedHub) string { return hub.Id },
+		key:  func(hub model.AggregatedHub) string { return hub.Name },
+		desc: direction == "desc",
+	}
+	if field == "log_state" {
+		result.key = func(hub model.AggregatedHub) string { return string(hub.ConnectionState) }
+		result.tied = true
+	}
+	return result
+}
+
+// listDevices reads the query parameters limit, offset, cursor, log, expand and the filters of parseDeviceQuery of device list requests
+func listDevices(ctx context.Context, lib pkg.Interface, r *http.Request) (result model.Page[model.AggregatedDevice], err error, code int) {
+	token, err := auth.GetParsedToken(r)
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	query, err := parseDeviceQuery(r)
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	expansions, expand, err := parseDeviceExpansions(r)
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	query.SkipDeviceType = expand && !expansions.NeedsDeviceType()
+	result, err, code = listPage(r, deviceOrder(query.SortBy), func(limit int64, offset int64) ([]model.AggregatedDevice, int64, error) {
+		return lib.FindDevices(ctx, token, query, limit, offset)
+	})
+	if err != nil {
+		return result, err, code
+	}
+	if expand {
+		result.Items, err = lib.ExpandDevices(ctx, token, expansions, result.Items)
 		if err != nil {
-			log.Println("ERROR: unable to encode response", err)
+			return result, err, http.StatusInternalServerError
 		}
-		return
+	} else if logDuration := r.URL.Query().Get("log"); logDuration != "" {
+		result.Items, err = lib.CompleteDeviceHistory(ctx, token, logDuration, result.Items)
+		if err != nil {
+			return result, err, http.StatusInternalServerError
+		}
+	}
+	return result, nil, http.StatusOK
+}
+
+// lookupDevices reads a model.DeviceLookup from the request body and returns the enriched devices like listDevices
+func lookupDevices(ctx context.Context, lib pkg.Interface, r *http.Request) (result model.DeviceLookupResult, err error, code int) {
+	token, err := auth.GetParsedToken(r)
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	lookup := model.DeviceLookup{}
+	err = json.NewDecoder(r.Body).Decode(&lookup)
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	if maxIds := lib.Config().MaxLookupIds; maxIds > 0 && int64(len(lookup.Ids)) > maxIds {
+		return result, fmt.Errorf("too many ids, expected at most %d", maxIds), http.StatusBadRequest
+	}
+	expand := lookup.Expand != nil
+	expansions, err := pkg.ParseDeviceExpansions(lookup.Expand, lookup.Log)
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	result.Devices, result.Errors, err = lib.LookupDevices(ctx, token, lookup.Ids, !expand || expansions.NeedsDeviceType())
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	if expand {
+		result.Devices, err = lib.ExpandDevices(ctx, token, expansions, result.Devices)
+	} else if lookup.Log != "" {
+		result.Devices, err = lib.CompleteDeviceHistory(ctx, token, lookup.Log, result.Devices)
+	}
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	return result, nil, http.StatusOK
+}
+
+// getDevice reads the query parameters log and expand of device detail requests.
+// unlike device lists, all expansions are added by default and the log duration defaults to defaultLogDuration.
+func getDevice(ctx context.Context, lib pkg.Interface, r *http.Request, id string) (result model.AggregatedDevice, err error, code int) {
+	token, err := auth.GetParsedToken(r)
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	values := r.URL.Query()
+	logDuration := values.Get("log")
+	if logDuration == "" {
+		logDuration = defaultLogDuration
+	}
+	names := pkg.DeviceExpansionNames
+	if values.Has("expand") {
+		names = splitList(values.Get("expand"))
+	}
+	expansions, err := pkg.ParseDeviceExpansions(names, logDuration)
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	return lib.GetDevice(ctx, token, id, expansions)
+}
+
+// listHubs reads the query parameters limit, offset, cursor, log and the filters of parseHubQuery of hub list requests
+func listHubs(ctx context.Context, lib pkg.Interface, r *http.Request) (result model.Page[model.AggregatedHub], err error, code int) {
+	token, err := auth.GetParsedToken(r)
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	query, err := parseHubQuery(r)
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	result, err, code = listPage(r, hubOrder(query.SortBy), func(limit int64, offset int64) ([]model.AggregatedHub, int64, error) {
+		return lib.ListGateways(ctx, token, query, limit, offset)
 	})
+	if err != nil {
+		return result, err, code
+	}
+	if logDuration := r.URL.Query().Get("log"); logDuration != "" {
+		result.Items, err = lib.CompleteGatewayHistory(ctx, token, logDuration, result.Items)
+		if err != nil {
+			return result, err, http.StatusInternalServerError
+		}
+	}
+	return result, nil, http.StatusOK
+}
 
-	return
+func listProcesses(ctx context.Context, lib pkg.Interface, r *http.Request) (result []model.AggregatedProcess, err error, code int) {
+	slog.DebugContext(ctx, "list processes", "query", r.URL.Query().Encode())
+	token, err := auth.GetParsedToken(r)
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	result, err = lib.GetExtendedProcessList(ctx, token, r.URL.Query())
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	return result, nil, http.StatusOK
+}
+
+// parseDeviceQuery reads the query parameters search, sort, device_type_ids, device_class_ids, attribute, log_state and owner
+func parseDeviceQuery(r *http.Request) (query pkg.DeviceQuery, err error) {
+	values := r.URL.Query()
+	query = pkg.DeviceQuery{
+		Search:         values.Get("search"),
+		SortBy:         values.Get("sort"),
+		DeviceTypeIds:  splitList(values.Get("device_type_ids")),
+		DeviceClassIds: splitList(values.Get("device_class_ids")),
+		LogState:       values.Get("log_state"),
+		Owner:          values.Get("owner"),
+	}
+	for _, attribute := range values["attribute"] {
+		key, value, ok := strings.Cut(attribute, ":")
+		if !ok {
+			return query, errors.New("expected attribute as <key>:<value>")
+		}
+		if query.Attributes == nil {
+			query.Attributes = map[string]string{}
+		}
+		query.Attributes[key] = value
+	}
+	return query, query.Validate()
+}
 
+// parseHubQuery reads the query parameters search, sort, log_state, device_id, min_devices and max_devices
+func parseHubQuery(r *http.Request) (query pkg.HubQuery, err error) {
+	values := r.URL.Query()
+	query = pkg.HubQuery{
+		Search:   values.Get("search"),
+		SortBy:   values.Get("sort"),
+		LogState: values.Get("log_state"),
+		DeviceId: values.Get("device_id"),
+	}
+	if values.Has("min_devices") {
+		query.MinDevices, err = strconv.ParseInt(values.Get("min_devices"), 10, 64)
+		if err != nil {
+			return query, fmt.Errorf("min_devices is not a number: %w", err)
+		}
+	}
+	if values.Has("max_devices") {
+		maxDevices, err := strconv.ParseInt(values.Get("max_devices"), 10, 64)
+		if err != nil {
+			return query, fmt.Errorf("max_devices is not a number: %w", err)
+		}
+		query.MaxDevices = &maxDevices
+	}
+	return query, query.Validate()
+}
+
+// parseDeviceExpansions reads the expand and log query parameters; expand is false if the expand parameter is not set
+func parseDeviceExpansions(r *http.Request) (expansions pkg.DeviceExpansions, expand bool, err error) {
+	values := r.URL.Query()
+	if !values.Has("expand") {
+		return expansions, false, nil
+	}
+	expansions, err = pkg.ParseDeviceExpansions(splitList(values.Get("expand")), values.Get("log"))
+	return expansions, true, err
+}
+
+// splitList returns the elements of a comma separated list or nil if list is empty
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
+// parseLimitOffset reads the limit (default 100) and offset (default 0) query parameters
+func parseLimitOffset(r *http.Request) (limit int64, offset int64, err error) {
+	limitStr, offsetStr := limitOffsetDefault(r.URL.Query().Get("limit"), r.URL.Query().Get("offset"))
+	limit, err = strconv.ParseInt(limitStr, 10, 64)
+	if err != nil {
+		return limit, offset, fmt.Errorf("limit is not a number: %w", err)
+	}
+	offset, err = strconv.ParseInt(offsetStr, 10, 64)
+	if err != nil {
+		return limit, offset, fmt.Errorf("offset is not a number: %w", err)
+	}
+	return limit, offset, nil
+}
+
+// handleError responds with err. server side errors are logged.
+func handleError(ctx context.Context, writer http.ResponseWriter, err error, code int) {
+	if code >= 500 {
+		logError(ctx, err)
+	}
+	http.Error(writer, err.Error(), code)
+}
+
+// logError logs the error of a failed request. errors caused by 4xx responses of upstream services are expected
+// (e.g. missing permissions) and logged as warnings.
+func logError(ctx context.Context, err error) {
+	args := []any{"error", err}
+	level := slog.LevelError
+	var responseErr *upstream.ResponseError
+	if errors.As(err, &responseErr) {
+		args = append(args, "upstream", responseErr.Upstream, "status_code", responseErr.StatusCode)
+		if responseErr.StatusCode < 500 {
+			level = slog.LevelWarn
+		}
+	}
+	slog.Log(ctx, level, "request failed", args...)
+}
+
+// setWarningsHeader lists the degraded enrichment steps of a partial response as json in the WarningsHeader
+func setWarningsHeader(writer http.ResponseWriter, ctx context.Context) {
+	warnings := pkg.GetWarnings(ctx)
+	if len(warnings) == 0 {
+		return
+	}
+	value, err := json.Marshal(warnings)
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to encode warnings", "error", err)
+		return
+	}
+	writer.Header().Set(WarningsHeader, string(value))
 }
 
 func limitOffsetDefault(limit, offset string) (string, string) {
@@ -344,7 +848,7 @@ func getSortParts(sort string) (orderfeature string, direction string) {
 	return
 }
 
-func isFunctionLoaded(functions []pkg.Function, id string) bool {
+func isFunctionLoaded(functions []model.MeasuringFunction, id string) bool {
 	for i := range functions {
 		if functions[i].Id == id {
 			return true
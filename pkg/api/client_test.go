@@ -0,0 +1,186 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/SENERGY-Platform/api-aggregator/pkg"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/auth"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/client"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/metrics"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	lib := &libMock{
+		devices: []model.AggregatedDevice{
+			{ExtendedDevice: models.ExtendedDevice{Device: models.Device{Id: "d1", Name: "d1"}}, LogState: model.LogStateConnected},
+			{ExtendedDevice: models.ExtendedDevice{Device: models.Device{Id: "d2", Name: "d2"}}, LogState: model.LogStateUnknown},
+		},
+		hubs: []model.AggregatedHub{
+			{ExtendedHub: models.ExtendedHub{Hub: models.Hub{Id: "h1", Name: "h1", DeviceIds: []string{"d1"}}}, LogState: model.LogStateDisconnected},
+		},
+	}
+	server := httptest.NewServer(getRoutes(lib))
+	defer server.Close()
+	c := client.NewClient(server.URL, nil)
+	ctx := context.Background()
+
+	t.Run("list devices", func(t *testing.T) {
+		page, err, _ := c.ListDevices(ctx, testToken, client.DeviceListOptions{Limit: 10, Offset: 1, Log: "1h"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := page.Items
+		if lib.limit != 10 || lib.offset != 1 || lib.log != "1h" {
+			t.Error(lib.limit, lib.offset, lib.log)
+		}
+		if len(result) != 2 || result[1].LogHistory == nil || result[1].Id != "d2" {
+			t.Errorf("%#v", result)
+		}
+	})
+
+	t.Run("list hubs", func(t *testing.T) {
+		page, err, _ := c.ListHubs(ctx, testToken, client.HubListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := page.Items
+		if lib.limit != 100 || lib.offset != 0 {
+			t.Error(lib.limit, lib.offset)
+		}
+		if !reflect.DeepEqual(result, lib.hubs) {
+			t.Errorf("%#v", result)
+		}
+	})
+
+	t.Run("list processes", func(t *testing.T) {
+		result, err, _ := c.ListProcesses(ctx, testToken, url.Values{"name": {"foo"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result) != 1 || result[0].Name != "foo" || *result[0].Online != true {
+			t.Errorf("%#v", result)
+		}
+	})
+
+	t.Run("measuring functions", func(t *testing.T) {
+		result, err, _ := c.GetMeasuringFunctionsForAspect(ctx, testToken, "urn:aspect:1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result) != 1 || result[0].Id != "f:urn:aspect:1" {
+			t.Errorf("%#v", result)
+		}
+	})
+
+	t.Run("typed errors", func(t *testing.T) {
+		_, err, code := c.ListDevices(ctx, "", client.DeviceListOptions{})
+		statusErr := &client.StatusError{}
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest || code != http.StatusBadRequest {
+			t.Error(err, code)
+		}
+		lib.err = errors.New("upstream failed")
+		defer func() { lib.err = nil }()
+		_, err, code = c.GetAspectNodesWithMeasuringFunction(ctx, testToken)
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway || statusErr.Message != "upstream failed\n" || code != http.StatusBadGateway {
+			t.Error(err, code)
+		}
+	})
+}
+
+// testToken is an unsigned jwt; the aggregator does not validate tokens
+var testToken = "Bearer " + base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"test-user"}`)) + ".sig"
+
+// libMock implements the parts of pkg.Interface used by the tests; other methods panic
+type libMock struct {
+	pkg.Interface
+	devices []model.AggregatedDevice
+	hubs    []model.AggregatedHub
+	err     error
+
+	limit  int64
+	offset int64
+	log    string
+}
+
+func (this *libMock) Config() pkg.Config {
+	return pkg.Config{}
+}
+
+func (this *libMock) Metrics() *metrics.Metrics {
+	return metrics.New()
+}
+
+func (this *libMock) FindDevices(ctx context.Context, token auth.Token, query pkg.DeviceQuery, limit int64, offset int64) ([]model.AggregatedDevice, int64, error) {
+	this.limit, this.offset = limit, offset
+	return this.devices, int64(len(this.devices)), this.err
+}
+
+func (this *libMock) CompleteDeviceHistory(ctx context.Context, token auth.Token, duration string, devices []model.AggregatedDevice) (result []model.AggregatedDevice, err error) {
+	this.log = duration
+	for _, device := range devices {
+		device.LogHistory = &model.HistorySeries{Name: device.Id}
+		result = append(result, device)
+	}
+	return result, this.err
+}
+
+func (this *libMock) ListGateways(ctx context.Context, token auth.Token, query pkg.HubQuery, limit int64, offset int64) ([]model.AggregatedHub, int64, error) {
+	this.limit, this.offset = limit, offset
+	return this.hubs, int64(len(this.hubs)), this.err
+}
+
+func (this *libMock) GetExtendedProcessList(ctx context.Context, token auth.Token, query url.Values) ([]model.AggregatedProcess, error) {
+	online := true
+	return []model.AggregatedProcess{{
+		ProcessDeployment: model.ProcessDeployment{Id: "p1", Name: query.Get("name")},
+		Online:            &online,
+		OnlineState:       model.OnlineStateOnline,
+	}}, this.err
+}
+
+func (this *libMock) GetMeasuringFunctionsForAspect(ctx context.Context, token auth.Token, aspectId string) ([]model.MeasuringFunction, error, int) {
+	return []model.MeasuringFunction{{Id: "f:" + aspectId}}, this.err, http.StatusOK
+}
+
+func (this *libMock) GetAspectNodes(ctx context.Context, ids []string, token auth.Token) (result []model.AspectNode, err error) {
+	for _, id := range ids {
+		result = append(result, model.AspectNode{Id: id})
+	}
+	return result, this.err
+}
+
+func (this *libMock) GetImportTypesWithAspect(ctx context.Context, token auth.Token, aspectIds []string) ([]pkg.ImportTypeWithCriteria, error, int) {
+	return nil, this.err, http.StatusOK
+}
+
+func (this *libMock) GetMeasuringFunctions(ctx context.Context, token auth.Token, functionIds []string) ([]model.MeasuringFunction, error, int) {
+	return nil, this.err, http.StatusOK
+}
+
+func (this *libMock) GetAspectNodesWithMeasuringFunction(ctx context.Context, token auth.Token) ([]model.AspectNode, error) {
+	return []model.AspectNode{{Id: "a1"}}, this.err
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// cursor points to the element next to a page (anchor). the page is searched by the sort key of the anchor
+// around its last known offset, so elements that are renamed, added or removed while paging are neither skipped nor duplicated.
+// upstream lists are sorted by the key only, elements with the same key (ties) are located by the last known offset.
+type cursor struct {
+	Offset int64  `json:"o"`
+	Id     string `json:"id"`
+	Key    string `json:"k"`
+	Prev   bool   `json:"p,omitempty"` //the page ends before the anchor; otherwise it starts after the anchor
+}
+
+func (this cursor) String() string {
+	value, _ := json.Marshal(this)
+	return base64.RawURLEncoding.EncodeToString(value)
+}
+
+func parseCursor(value string) (result cursor, err error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err == nil {
+		err = json.Unmarshal(decoded, &result)
+	}
+	if err != nil || result.Id == "" || result.Offset < 0 {
+		return result, errors.New("invalid cursor")
+	}
+	return result, nil
+}
+
+// order describes the sort order of a paginated list
+type order[T any] struct {
+	id   func(T) string
+	key  func(T) string //the sort value, e.g. the name
+	desc bool
+	tied bool //most elements share a key (e.g. log_state); cursors are not supported
+}
+
+// after returns true if element is sorted after the anchor of c
+func (this order[T]) after(element T, c cursor) bool {
+	if this.desc {
+		return this.key(element) < c.Key
+	}
+	return this.key(element) > c.Key
+}
+
+// listPage returns the page selected by the cursor query parameter or, if no cursor is given, by limit and offset.
+// list is called with limit and offset of the upstream list (e.g. lib.FindDevices), which has to be sorted by o,
+// and returns the elements and the total count of the list.
+func listPage[T any](r *http.Request, o order[T], list func(limit int64, offset int64) ([]T, int64, error)) (page model.Page[T], err error, code int) {
+	page.Limit, page.Offset, err = parseLimitOffset(r)
+	if err != nil {
+		return page, err, http.StatusBadRequest
+	}
+	if value := r.URL.Query().Get("cursor"); value != "" {
+		if page.Limit <= 0 {
+			return page, errors.New("limit has to be greater than 0 to use a cursor"), http.StatusBadRequest
+		}
+		if o.tied {
+			return page, errors.New("cursor is not supported for this sort field, use offset"), http.StatusBadRequest
+		}
+		c, err := parseCursor(value)
+		if err != nil {
+			return page, err, http.StatusBadRequest
+		}
+		page.Items, page.Offset, page.Total, err = listByCursor(c, page.Limit, o, list)
+		if err != nil {
+			return page, err, http.StatusInternalServerError
+		}
+	} else {
+		page.Items, page.Total, err = list(page.Limit, page.Offset)
+		if err != nil {
+			return page, err, http.StatusInternalServerError
+		}
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	page.HasMore = page.Offset+int64(len(page.Items)) < page.Total
+	if o.tied {
+		return page, nil, http.StatusOK
+	}
+	if len(page.Items) > 0 && page.Limit > 0 && page.HasMore {
+		last := page.Items[len(page.Items)-1]
+		page.NextCursor = cursor{Offset: page.Offset + int64(len(page.Items)) - 1, Id: o.id(last), Key: o.key(last)}.String()
+	}
+	if len(page.Items) > 0 && page.Offset > 0 {
+		first := page.Items[0]
+		page.PrevCursor = cursor{Offset: page.Offset, Id: o.id(first), Key: o.key(first), Prev: true}.String()
+	}
+	return page, nil, http.StatusOK
+}
+
+// listByCursor searches the position of the anchor of c in a window around its last known offset:
+// the position of the anchor itself if its sort key is unchanged, otherwise the first element sorted after the anchor key.
+// if the window contains elements with the key of a removed or renamed anchor, their order is unknown:
+// the anchor position is derived from its last known offset, limited to these elements.
+func listByCursor[T any](c cursor, limit int64, o order[T], list func(limit int64, offset int64) ([]T, int64, error)) (result []T, offset int64, total int64, err error) {
+	windowOffset := max(0, c.Offset-2*limit)
+	window, total, err := list(4*limit, windowOffset)
+	if err != nil {
+		return result, offset, total, err
+	}
+	windowEnd := windowOffset + int64(len(window))
+	complete := int64(len(window)) < 4*limit //the window reaches the end of the list
+
+	anchor, exact := int64(-1), false
+	for i, element := range window {
+		if o.id(element) == c.Id && o.key(element) == c.Key {
+			anchor, exact = windowOffset+int64(i), true
+			break
+		}
+	}
+	if !exact {
+		tieStart, tieEnd, moved := int64(-1), int64(-1), int64(-1)
+		for i, element := range window {
+			position := windowOffset + int64(i)
+			switch {
+			case o.id(element) == c.Id:
+				moved = position
+			case o.key(element) == c.Key:
+				if tieStart < 0 {
+					tieStart = position
+				}
+				tieEnd = position + 1
+			case anchor < 0 && o.after(element, c):
+				anchor = position
+			}
+		}
+		if tieStart >= 0 {
+			anchor = c.Offset //removed or moved behind: the following elements moved up
+			if moved >= 0 && moved < c.Offset {
+				anchor = c.Offset + 1 //moved ahead: the preceding elements moved down
+			}
+			anchor = min(max(anchor, tieStart), tieEnd)
+		}
+	}
+	if anchor < 0 {
+		anchor = c.Offset + 1 //the window does not contain elements after the key
+		if complete {
+			anchor = windowEnd
+		}
+	}
+
+	start, end := anchor, anchor+limit
+	if exact {
+		start, end = anchor+1, anchor+1+limit
+	}
+	if c.Prev {
+		start, end = max(0, anchor-limit), anchor
+	}
+	if start >= windowOffset && (end <= windowEnd || complete) {
+		return window[min(start-windowOffset, int64(len(window))):min(end-windowOffset, int64(len(window)))], start, total, nil
+	}
+	result, total, err = list(end-start, start)
+	return result, start, total, err
+}
+
+// setPageHeaders sets the X-Total-Count header and adds Link headers (RFC 8288) with the urls of the next and previous page
+func setPageHeaders[T any](writer http.ResponseWriter, r *http.Request, page model.Page[T]) {
+	writer.Header().Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	links := []string{}
+	for _, link := range []struct{ rel, cursor string }{{"next", page.NextCursor}, {"prev", page.PrevCursor}} {
+		if link.cursor == "" {
+			continue
+		}
+		query := r.URL.Query()
+		query.Del("offset")
+		query.Set("cursor", link.cursor)
+		target := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+		links = append(links, "<"+target.String()+`>; rel="`+link.rel+`"`)
+	}
+	if len(links) > 0 {
+		writer.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
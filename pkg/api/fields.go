@@ -0,0 +1,64 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"net/http"
+	"slices"
+)
+
+// parseFields reads the fields query parameter (comma separated top level json fields); nil selects all fields
+func parseFields(r *http.Request) []string {
+	return splitList(r.URL.Query().Get("fields"))
+}
+
+// selectFields removes the top level fields that are not in fields from the elements of list (sparse fieldset).
+// list is not changed if fields is empty.
+func selectFields(list []map[string]interface{}, fields []string) []map[string]interface{} {
+	if len(fields) == 0 {
+		return list
+	}
+	for _, element := range list {
+		for key := range element {
+			if !slices.Contains(fields, key) {
+				delete(element, key)
+			}
+		}
+	}
+	return list
+}
+
+// pageWithFields returns page with items reduced to fields
+func pageWithFields[T any](page model.Page[T], fields []string) (result model.Page[map[string]interface{}], err error) {
+	items, err := toMaps(page.Items)
+	if err != nil {
+		return result, err
+	}
+	if items == nil {
+		items = []map[string]interface{}{}
+	}
+	return model.Page[map[string]interface{}]{
+		Items:      selectFields(items, fields),
+		Total:      page.Total,
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+		HasMore:    page.HasMore,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	}, nil
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+)
+
+// legacyDevices converts devices to the untyped representation of the /devices endpoint.
+// if the log history was requested, log_history and log_edge are always set (null if unavailable).
+func legacyDevices(devices []model.AggregatedDevice, withLog bool) (result []map[string]interface{}, err error) {
+	result, err = toMaps(devices)
+	if err != nil {
+		return nil, err
+	}
+	if withLog {
+		setMissingLogFields(result)
+	}
+	return result, nil
+}
+
+// legacyHubs converts hubs to the untyped representation of the /hubs endpoint.
+// if the log history was requested, log_history and log_edge are always set (null if unavailable).
+func legacyHubs(hubs []model.AggregatedHub, withLog bool) (result []map[string]interface{}, err error) {
+	result, err = toMaps(hubs)
+	if err != nil {
+		return nil, err
+	}
+	if withLog {
+		setMissingLogFields(result)
+	}
+	return result, nil
+}
+
+func legacyProcesses(processes []model.AggregatedProcess) (result []map[string]interface{}, err error) {
+	return toMaps(processes)
+}
+
+func setMissingLogFields(list []map[string]interface{}) {
+	for _, element := range list {
+		for _, field := range []string{"log_history", "log_edge"} {
+			if _, ok := element[field]; !ok {
+				element[field] = nil
+			}
+		}
+	}
+}
+
+// toMaps returns nil for empty lists, to keep the null responses of the legacy endpoints
+func toMaps[T any](list []T) (result []map[string]interface{}, err error) {
+	for _, element := range list {
+		temp, err := json.Marshal(element)
+		if err != nil {
+			return nil, err
+		}
+		m := map[string]interface{}{}
+		err = json.Unmarshal(temp, &m)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
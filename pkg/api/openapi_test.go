@@ -0,0 +1,58 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestOpenApiSpec compares the routes registered by getRoutes with the paths of the OpenAPI document
+func TestOpenApiSpec(t *testing.T) {
+	spec := struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}{}
+	err := json.Unmarshal(OpenApiSpec, &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	documented := map[string]bool{}
+	for path, methods := range spec.Paths {
+		for method := range methods {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	registered := getRoutes(&libMock{}).Routes()
+	if len(registered) == 0 {
+		t.Fatal("no routes found")
+	}
+	for _, route := range registered {
+		key := route.Method + " " + routeParam.ReplaceAllString(route.Path, "{$1}")
+		if !documented[key] {
+			t.Error("route is missing in openapi.json:", key)
+		}
+		delete(documented, key)
+	}
+	for route := range documented {
+		t.Error("openapi.json documents unknown route:", route)
+	}
+}
+
+var routeParam = regexp.MustCompile(`:(\w+)`)
@@ -0,0 +1,48 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"github.com/SENERGY-Platform/api-aggregator/pkg/auth"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/logging"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+// NewLogging adds the request id and the user id to the request context, so every log record of the request contains them.
+// the request id is taken from the X-Request-Id header or generated and is returned as X-Request-Id response header.
+func NewLogging(handler http.Handler) http.Handler {
+	return &LoggingMiddleware{handler: handler}
+}
+
+type LoggingMiddleware struct {
+	handler http.Handler
+}
+
+func (this *LoggingMiddleware) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	id := req.Header.Get(logging.RequestIdHeader)
+	if id == "" {
+		id = uuid.NewString()
+		req.Header.Set(logging.RequestIdHeader, id)
+	}
+	res.Header().Set(logging.RequestIdHeader, id)
+	ctx := logging.WithRequestId(req.Context(), id)
+	if token, err := auth.GetParsedToken(req); err == nil {
+		ctx = logging.With(ctx, "user_id", token.GetUserId())
+	}
+	this.handler.ServeHTTP(res, req.WithContext(ctx))
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"net/http"
+	"time"
+)
+
+type RequestObserver interface {
+	ObserveRequest(route string, method string, status int, duration time.Duration)
+}
+
+// NewMetrics reports every request handled by handler to observer, labeled by the route matched by a Router (e.g. /aspects/:id/measuring-functions)
+func NewMetrics(handler http.Handler, observer RequestObserver) http.Handler {
+	return &MetricsMiddleware{handler: handler, observer: observer}
+}
+
+type MetricsMiddleware struct {
+	handler  http.Handler
+	observer RequestObserver
+}
+
+func (this *MetricsMiddleware) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	recorder := &statusRecorder{ResponseWriter: res, status: http.StatusOK}
+	req, route := withRoute(req)
+	this.handler.ServeHTTP(recorder, req)
+	this.observer.ObserveRequest(*route, req.Method, recorder.status, time.Since(start))
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (this *statusRecorder) WriteHeader(status int) {
+	if !this.wroteHeader {
+		this.status = status
+		this.wroteHeader = true
+	}
+	this.ResponseWriter.WriteHeader(status)
+}
+
+func (this *statusRecorder) Write(b []byte) (int, error) {
+	this.wroteHeader = true
+	return this.ResponseWriter.Write(b)
+}
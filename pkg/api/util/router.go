@@ -0,0 +1,85 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"context"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"slices"
+)
+
+// Router is a httprouter.Router that reports the matched route of a request (e.g. /aspects/:id/measuring-functions)
+// to the middlewares of NewMetrics and NewTracing
+type Router struct {
+	*httprouter.Router
+	routes []Route
+}
+
+// Route is a method and path registered at a Router
+type Route struct {
+	Method string
+	Path   string
+}
+
+func NewRouter() *Router {
+	router := httprouter.New()
+	router.SaveMatchedRoutePath = true
+	return &Router{Router: router}
+}
+
+func (this *Router) Handle(method string, path string, handle httprouter.Handle) {
+	this.routes = append(this.routes, Route{Method: method, Path: path})
+	this.Router.Handle(method, path, func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		if route, ok := request.Context().Value(routeKey{}).(*string); ok {
+			*route = params.MatchedRoutePath()
+		}
+		handle(writer, request, params)
+	})
+}
+
+func (this *Router) GET(path string, handle httprouter.Handle) {
+	this.Handle(http.MethodGet, path, handle)
+}
+
+func (this *Router) POST(path string, handle httprouter.Handle) {
+	this.Handle(http.MethodPost, path, handle)
+}
+
+func (this *Router) Handler(method string, path string, handler http.Handler) {
+	this.Handle(method, path, func(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+		handler.ServeHTTP(writer, request)
+	})
+}
+
+// Routes returns the registered routes in the order of their registration
+func (this *Router) Routes() []Route {
+	return slices.Clone(this.routes)
+}
+
+type routeKey struct{}
+
+// withRoute returns a request that receives the matched route of a Router in route.
+// route is "unmatched" if no route handles the request.
+func withRoute(request *http.Request) (result *http.Request, route *string) {
+	if route, ok := request.Context().Value(routeKey{}).(*string); ok {
+		return request, route
+	}
+	route = new(string)
+	*route = "unmatched"
+	return request.WithContext(context.WithValue(request.Context(), routeKey{}, route)), route
+}
@@ -0,0 +1,36 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// NewTracing starts a server span for every request, continuing the trace of an incoming traceparent header.
+// spans are named by method and the route matched by a Router (e.g. "GET /aspects/:id/measuring-functions")
+func NewTracing(handler http.Handler) http.Handler {
+	return otelhttp.NewHandler(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		req, route := withRoute(req)
+		handler.ServeHTTP(res, req)
+		//the route is known after routing, the span is named by the method until then
+		trace.SpanFromContext(req.Context()).SetName(req.Method + " " + *route)
+	}), "api-aggregator", otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+		return req.Method
+	}))
+}
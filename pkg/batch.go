@@ -0,0 +1,119 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ChunkError reports which chunk of a batched id-list call failed
+type ChunkError struct {
+	Chunk  int //starting with 0
+	Chunks int
+	Ids    []string
+	Err    error
+}
+
+func (this *ChunkError) Error() string {
+	return fmt.Sprintf("chunk %v of %v (%v ids starting with %v): %v", this.Chunk+1, this.Chunks, len(this.Ids), this.Ids[0], this.Err)
+}
+
+func (this *ChunkError) Unwrap() error {
+	return this.Err
+}
+
+// chunks splits ids into chunks of IdBatchSize; an empty list results in one empty chunk
+func (this *Lib) chunks(ids []string) [][]string {
+	if this.config.IdBatchSize <= 0 || len(ids) <= int(this.config.IdBatchSize) {
+		return [][]string{ids}
+	}
+	return slices.Collect(slices.Chunk(ids, int(this.config.IdBatchSize)))
+}
+
+// batchMap calls f concurrently for chunks of ids and merges the results
+func batchMap[V any](ctx context.Context, this *Lib, ids []string, f func(ctx context.Context, ids []string) (map[string]V, error)) (result map[string]V, err error) {
+	results, err := batch(ctx, this, ids, f)
+	result = map[string]V{}
+	for _, r := range results {
+		maps.Copy(result, r)
+	}
+	return result, err
+}
+
+// batchList calls f concurrently for chunks of ids and concatenates the results in the order of the chunks
+func batchList[V any](ctx context.Context, this *Lib, ids []string, f func(ctx context.Context, ids []string) ([]V, error)) (result []V, err error) {
+	results, err := batch(ctx, this, ids, f)
+	for _, r := range results {
+		result = append(result, r...)
+	}
+	return result, err
+}
+
+func batch[R any](ctx context.Context, this *Lib, ids []string, f func(ctx context.Context, ids []string) (R, error)) (results []R, err error) {
+	chunks := this.chunks(ids)
+	if len(chunks) == 1 {
+		result, err := f(ctx, chunks[0])
+		return []R{result}, err
+	}
+	results = make([]R, len(chunks))
+	group, groupCtx := this.newGroup(ctx)
+	for i, chunk := range chunks {
+		group.Go(func() error {
+			result, err := f(groupCtx, chunk)
+			if err != nil {
+				return &ChunkError{Chunk: i, Chunks: len(chunks), Ids: chunk, Err: err}
+			}
+			results[i] = result
+			return nil
+		})
+	}
+	err = group.Wait()
+	return results, err
+}
+
+// listBatchSize is the page size used to read complete lists of the device-repository
+const listBatchSize = 1000
+
+// listAll reads all elements of a paginated list in pages of listBatchSize
+func listAll[T any](list func(limit int64, offset int64) ([]T, int64, error)) (result []T, err error) {
+	var offset int64 = 0
+	for {
+		temp, total, err := list(listBatchSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, temp...)
+		offset += int64(len(temp))
+		if int64(len(temp)) < listBatchSize || offset >= total {
+			return result, nil
+		}
+	}
+}
+
+// page returns the elements of list selected by limit and offset and the total count of list.
+// a limit <= 0 selects all elements after offset.
+func page[T any](list []T, limit int64, offset int64) (result []T, total int64) {
+	total = int64(len(list))
+	start, end := min(max(offset, 0), total), total
+	if limit > 0 {
+		end = min(start+limit, total)
+	}
+	return list[start:end], total
+}
@@ -0,0 +1,199 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package cache provides a size limited in-memory cache with time-to-live for rarely changing upstream resources.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache keeps up to maxEntries values for ttl. if the cache is full, the least recently used entry is evicted.
+// a nil *Cache is valid and caches nothing.
+type Cache[V any] struct {
+	name       string
+	ttl        time.Duration
+	maxEntries int
+
+	mux       sync.Mutex
+	entries   map[string]*list.Element
+	lru       *list.List //front is the most recently used entry
+	hits      int64
+	misses    int64
+	evictions int64
+	cleared   int64
+	//generation is incremented by Invalidate and Clear, so Use can detect invalidations during a load
+	generation uint64
+	now        func() time.Time
+}
+
+type entry[V any] struct {
+	key     string
+	value   V
+	expires time.Time
+}
+
+type Stats struct {
+	Name       string `json:"name"`
+	Ttl        string `json:"ttl"`
+	MaxEntries int    `json:"max_entries"` //0 means unlimited
+	Entries    int    `json:"entries"`
+	Hits       int64  `json:"hits"`
+	Misses     int64  `json:"misses"`
+	Evictions  int64  `json:"evictions"`
+	Cleared    int64  `json:"cleared"` //count of Invalidate and Clear calls
+}
+
+// New creates a Cache; returns nil (no caching) if ttl <= 0. maxEntries <= 0 means no size limit.
+func New[V any](name string, ttl time.Duration, maxEntries int) *Cache[V] {
+	if ttl <= 0 {
+		return nil
+	}
+	return &Cache[V]{
+		name:       name,
+		ttl:        ttl,
+		maxEntries: max(maxEntries, 0),
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+		now:        time.Now,
+	}
+}
+
+func (this *Cache[V]) Get(key string) (value V, ok bool) {
+	if this == nil {
+		return value, false
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	element, ok := this.entries[key]
+	if !ok {
+		this.misses++
+		return value, false
+	}
+	e := element.Value.(*entry[V])
+	if !this.now().Before(e.expires) {
+		this.remove(element)
+		this.misses++
+		return value, false
+	}
+	this.lru.MoveToFront(element)
+	this.hits++
+	return e.value, true
+}
+
+func (this *Cache[V]) Set(key string, value V) {
+	if this == nil {
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.set(key, value)
+}
+
+// Use returns the cached value for key or calls load and caches its result if load returns no error.
+// the result is not cached if the cache is invalidated while load is running, because it may be outdated.
+// load gets the generation of the cache at its start: loads that are shared between callers (e.g. coalesced)
+// have to be keyed by it, otherwise a caller after an invalidation may share (and cache) a load started before it.
+func (this *Cache[V]) Use(key string, load func(generation uint64) (V, error)) (value V, err error) {
+	value, ok := this.Get(key)
+	if ok {
+		return value, nil
+	}
+	generation := this.getGeneration()
+	value, err = load(generation)
+	if err == nil {
+		this.setIfGeneration(key, value, generation)
+	}
+	return value, err
+}
+
+func (this *Cache[V]) getGeneration() uint64 {
+	if this == nil {
+		return 0
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return this.generation
+}
+
+func (this *Cache[V]) setIfGeneration(key string, value V, generation uint64) {
+	if this == nil {
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if this.generation == generation {
+		this.set(key, value)
+	}
+}
+
+func (this *Cache[V]) set(key string, value V) {
+	if element, ok := this.entries[key]; ok {
+		this.remove(element)
+	}
+	this.entries[key] = this.lru.PushFront(&entry[V]{key: key, value: value, expires: this.now().Add(this.ttl)})
+	for this.maxEntries > 0 && this.lru.Len() > this.maxEntries {
+		this.remove(this.lru.Back())
+		this.evictions++
+	}
+}
+
+func (this *Cache[V]) Invalidate(key string) {
+	if this == nil {
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.cleared++
+	this.generation++
+	if element, ok := this.entries[key]; ok {
+		this.remove(element)
+	}
+}
+
+func (this *Cache[V]) Clear() {
+	if this == nil {
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.cleared++
+	this.generation++
+	this.entries = map[string]*list.Element{}
+	this.lru.Init()
+}
+
+func (this *Cache[V]) Stats() Stats {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return Stats{
+		Name:       this.name,
+		Ttl:        this.ttl.String(),
+		MaxEntries: this.maxEntries,
+		Entries:    this.lru.Len(),
+		Hits:       this.hits,
+		Misses:     this.misses,
+		Evictions:  this.evictions,
+		Cleared:    this.cleared,
+	}
+}
+
+func (this *Cache[V]) remove(element *list.Element) {
+	this.lru.Remove(element)
+	delete(this.entries, element.Value.(*entry[V]).key)
+}
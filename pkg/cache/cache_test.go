@@ -0,0 +1,89 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Now()
+	c := New[int]("test", time.Minute, 2)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	if _, ok := c.Get("a"); !ok {
+		t.Error("missing a")
+	}
+	c.Set("c", 3) //evicts b, the least recently used entry
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should be evicted")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Error("a should be expired")
+	}
+
+	loads := 0
+	load := func(uint64) (int, error) {
+		loads++
+		return loads, nil
+	}
+	if value, _ := c.Use("d", load); value != 1 {
+		t.Error(value)
+	}
+	if value, _ := c.Use("d", load); value != 1 || loads != 1 {
+		t.Error(value, loads)
+	}
+	_, err := c.Use("e", func(uint64) (int, error) { return 0, errors.New("failed") })
+	if _, ok := c.Get("e"); err == nil || ok {
+		t.Error("failed loads should not be cached")
+	}
+
+	before := uint64(0)
+	_, _ = c.Use("f", func(generation uint64) (int, error) {
+		before = generation
+		c.Invalidate("f") //invalidation while loading
+		return 1, nil
+	})
+	if _, ok := c.Get("f"); ok {
+		t.Error("loads running during invalidations should not be cached")
+	}
+	_, _ = c.Use("f", func(generation uint64) (int, error) {
+		if generation == before {
+			t.Error("loads after invalidations should get a new generation")
+		}
+		return 1, nil
+	})
+	if _, ok := c.Get("f"); !ok {
+		t.Error("f should be cached")
+	}
+
+	stats := c.Stats() //expired entries (c) are removed on access
+	if stats.Entries != 2 || stats.Hits != 3 || stats.Misses != 8 || stats.Evictions != 2 {
+		t.Errorf("%#v", stats)
+	}
+
+	var disabled *Cache[int] = New[int]("disabled", 0, 0)
+	if value, _ := disabled.Use("a", load); value != 2 {
+		t.Error(value)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/auth"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/cache"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// caches of semantic metadata; aspects and functions are the same for all users, import types depend on the permissions of the user
+type caches struct {
+	aspectNodes        *cache.Cache[[]model.AspectNode]
+	measuringFunctions *cache.Cache[[]model.MeasuringFunction]
+	importTypes        *cache.Cache[[]ImportTypeWithCriteria]
+}
+
+func newCaches(config Config) caches {
+	maxEntries := int(config.CacheMaxEntries)
+	return caches{
+		aspectNodes:        cache.New[[]model.AspectNode]("aspect-nodes", parseDurationOrZero("aspect_cache_ttl", config.AspectCacheTtl), maxEntries),
+		measuringFunctions: cache.New[[]model.MeasuringFunction]("measuring-functions", parseDurationOrZero("function_cache_ttl", config.FunctionCacheTtl), maxEntries),
+		importTypes:        cache.New[[]ImportTypeWithCriteria]("import-types", parseDurationOrZero("import_type_cache_ttl", config.ImportTypeCacheTtl), maxEntries),
+	}
+}
+
+const aspectNodesWithMeasuringFunctionKey = "measuring-function"
+
+// GetCacheStats returns the stats of all enabled caches
+func (this *Lib) GetCacheStats() (result []cache.Stats) {
+	result = []cache.Stats{}
+	if this.caches.aspectNodes != nil {
+		result = append(result, this.caches.aspectNodes.Stats())
+	}
+	if this.caches.measuringFunctions != nil {
+		result = append(result, this.caches.measuringFunctions.Stats())
+	}
+	if this.caches.importTypes != nil {
+		result = append(result, this.caches.importTypes.Stats())
+	}
+	return result
+}
+
+func (this *Lib) GetAspectNodes(ctx context.Context, ids []string, token auth.Token) ([]model.AspectNode, error) {
+	return cached(this.caches.aspectNodes, "ids:"+idsKey(ids), func(generation uint64) ([]model.AspectNode, error) {
+		return coalesce(ctx, this, "aspect-nodes", atGeneration(idsKey(ids), generation), func(ctx context.Context) ([]model.AspectNode, error) {
+			return this.getAspectNodes(ctx, ids, token)
+		})
+	})
+}
+
+func (this *Lib) GetAspectNodesWithMeasuringFunction(ctx context.Context, token auth.Token) ([]model.AspectNode, error) {
+	return cached(this.caches.aspectNodes, aspectNodesWithMeasuringFunctionKey, func(generation uint64) ([]model.AspectNode, error) {
+		return coalesce(ctx, this, "aspect-nodes-with-measuring-function", atGeneration("", generation), func(ctx context.Context) ([]model.AspectNode, error) {
+			return this.getAspectNodesWithMeasuringFunction(ctx, token)
+		})
+	})
+}
+
+func (this *Lib) GetMeasuringFunctionsForAspect(ctx context.Context, token auth.Token, aspectId string) (functions []model.MeasuringFunction, err error, code int) {
+	code = http.StatusOK
+	functions, err = cached(this.caches.measuringFunctions, "aspect:"+aspectId, func(generation uint64) (result []model.MeasuringFunction, err error) {
+		result, err, code = coalesceWithCode(ctx, this, "aspect-measuring-functions", atGeneration(aspectId, generation), func(ctx context.Context) ([]model.MeasuringFunction, error, int) {
+			return this.getMeasuringFunctionsForAspect(ctx, token, aspectId)
+		})
+		return result, err
+	})
+	return functions, err, code
+}
+
+func (this *Lib) GetMeasuringFunctions(ctx context.Context, token auth.Token, functionIds []string) (functions []model.MeasuringFunction, err error, code int) {
+	code = http.StatusOK
+	functions, err = cached(this.caches.measuringFunctions, "ids:"+idsKey(functionIds), func(generation uint64) (result []model.MeasuringFunction, err error) {
+		result, err, code = coalesceWithCode(ctx, this, "measuring-functions", atGeneration(idsKey(functionIds), generation), func(ctx context.Context) ([]model.MeasuringFunction, error, int) {
+			return this.getMeasuringFunctions(ctx, token, functionIds)
+		})
+		return result, err
+	})
+	return functions, err, code
+}
+
+func (this *Lib) GetImportTypesWithAspect(ctx context.Context, token auth.Token, aspectIds []string) (importTypes []ImportTypeWithCriteria, err error, code int) {
+	code = http.StatusOK
+	importTypes, err = cached(this.caches.importTypes, token.GetUserId()+":aspects:"+idsKey(aspectIds), func(generation uint64) (result []ImportTypeWithCriteria, err error) {
+		result, err, code = coalesceWithCode(ctx, this, "import-types-with-aspect", atGeneration(token.GetUserId()+":"+idsKey(aspectIds), generation), func(ctx context.Context) ([]ImportTypeWithCriteria, error, int) {
+			return this.getImportTypesWithAspect(ctx, token, aspectIds)
+		})
+		return result, err
+	})
+	return importTypes, err, code
+}
+
+func (this *Lib) GetImportTypes(ctx context.Context, token auth.Token) (importTypes []ImportTypeWithCriteria, err error, code int) {
+	code = http.StatusOK
+	importTypes, err = cached(this.caches.importTypes, token.GetUserId()+":all", func(generation uint64) (result []ImportTypeWithCriteria, err error) {
+		result, err, code = coalesceWithCode(ctx, this, "import-types", atGeneration(token.GetUserId(), generation), func(ctx context.Context) ([]ImportTypeWithCriteria, error, int) {
+			return this.getImportTypes(ctx, token)
+		})
+		return result, err
+	})
+	return importTypes, err, code
+}
+
+// cached returns a copy of the cached (and possibly coalesced) list, so callers may append to it.
+// failed loads are not cached.
+func cached[T any](c *cache.Cache[[]T], key string, load func(generation uint64) ([]T, error)) ([]T, error) {
+	result, err := c.Use(key, load)
+	return slices.Clone(result), err
+}
+
+// atGeneration adds the cache generation of cache.Cache.Use to a coalescing key,
+// so callers after an invalidation do not share the outdated result of a load started before it
+func atGeneration(key string, generation uint64) string {
+	return key + "@" + strconv.FormatUint(generation, 10)
+}
+
+// idsKey is independent of the order of ids
+func idsKey(ids []string) string {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	return strings.Join(ids, ",")
+}
@@ -0,0 +1,31 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"net/url"
+)
+
+func (this *Client) GetAspectNodesWithMeasuringFunction(ctx context.Context, token string) (result []model.AspectNode, err error, code int) {
+	return get[[]model.AspectNode](ctx, this, token, "/aspect-nodes", url.Values{"function": {"measuring-function"}})
+}
+
+func (this *Client) GetMeasuringFunctionsForAspect(ctx context.Context, token string, aspectId string) (result []model.MeasuringFunction, err error, code int) {
+	return get[[]model.MeasuringFunction](ctx, this, token, "/aspects/"+url.PathEscape(aspectId)+"/measuring-functions", nil)
+}
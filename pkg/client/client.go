@@ -0,0 +1,115 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+type Interface interface {
+	ListDevices(ctx context.Context, token string, options DeviceListOptions) (result model.Page[model.AggregatedDevice], err error, code int)
+	GetDevice(ctx context.Context, token string, id string, options DeviceOptions) (result model.AggregatedDevice, err error, code int)
+	QueryDevices(ctx context.Context, token string, lookup model.DeviceLookup) (result model.DeviceLookupResult, err error, code int)
+	GetHub(ctx context.Context, token string, id string, log string) (result model.AggregatedHub, err error, code int)
+	ListHubs(ctx context.Context, token string, options HubListOptions) (result model.Page[model.AggregatedHub], err error, code int)
+	ListProcesses(ctx context.Context, token string, query url.Values) (result []model.AggregatedProcess, err error, code int)
+	GetDeviceDependents(ctx context.Context, token string, deviceId string) (result []model.Dependent, err error, code int)
+	QueryDependents(ctx context.Context, token string, lookup model.DependentsLookup) (result model.DependentsLookupResult, err error, code int)
+	GetDeviceClassUses(ctx context.Context, token string) (result model.DeviceClassUses, err error, code int)
+	GetAspectNodesWithMeasuringFunction(ctx context.Context, token string) (result []model.AspectNode, err error, code int)
+	GetMeasuringFunctionsForAspect(ctx context.Context, token string, aspectId string) (result []model.MeasuringFunction, err error, code int)
+}
+
+type Client struct {
+	baseUrl string
+	http    *http.Client
+}
+
+// NewClient creates a client for the api-aggregator reachable at baseUrl.
+// httpClient may be nil to use http.DefaultClient.
+func NewClient(baseUrl string, httpClient *http.Client) Interface {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseUrl: baseUrl, http: httpClient}
+}
+
+// StatusError is returned if the api-aggregator responds with a status code >= 300
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (this *StatusError) Error() string {
+	return fmt.Sprintf("unexpected statuscode %v: %v", this.StatusCode, this.Message)
+}
+
+func get[T any](ctx context.Context, c *Client, token string, path string, query url.Values) (result T, err error, code int) {
+	endpoint := c.baseUrl + path
+	if len(query) > 0 {
+		endpoint = endpoint + "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	return do[T](c, req)
+}
+
+func post[T any](ctx context.Context, c *Client, token string, path string, body interface{}) (result T, err error, code int) {
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseUrl+path, bytes.NewReader(buf))
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	return do[T](c, req)
+}
+
+func do[T any](c *Client, req *http.Request) (result T, err error, code int) {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode > 299 {
+		temp, _ := io.ReadAll(resp.Body) //read error response end ensure that resp.Body is read to EOF
+		return result, &StatusError{StatusCode: resp.StatusCode, Message: string(temp)}, resp.StatusCode
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		_, _ = io.ReadAll(resp.Body) //ensure resp.Body is read to EOF
+		return result, err, http.StatusInternalServerError
+	}
+	return result, nil, resp.StatusCode
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type DeviceListOptions struct {
+	Limit  int64  //default 100
+	Offset int64  //default 0
+	Cursor string //optional NextCursor or PrevCursor of a previous page; replaces Offset
+	Log    string //optional influxdb duration (e.g. 4h); adds log_history and log_edge
+
+	Search         string            //optional; name or display name contains
+	Sort           string            //optional <field>.<asc|desc> with field name, display_name or log_state
+	DeviceTypeIds  []string          //optional
+	DeviceClassIds []string          //optional
+	Attributes     map[string]string //optional attribute key -> value; all have to match
+	LogState       string            //optional connected, disconnected or unknown
+	Owner          string            //optional owner id
+
+	Fields []string //optional top level fields of the devices; other fields are omitted (zero values)
+	Expand []string //optional expansions (e.g. device_class); if set, Log only sets the duration of log_history and log_edge
+}
+
+type DeviceOptions struct {
+	Log    string   //optional influxdb duration of log_history and log_edge; defaults to 24h
+	Fields []string //optional top level fields of the device; other fields are omitted (zero values)
+	Expand []string //optional expansions (e.g. device_class); defaults to all expansions
+}
+
+func (this *Client) ListDevices(ctx context.Context, token string, options DeviceListOptions) (result model.Page[model.AggregatedDevice], err error, code int) {
+	query := url.Values{}
+	if options.Limit != 0 {
+		query.Set("limit", strconv.FormatInt(options.Limit, 10))
+	}
+	if options.Offset != 0 {
+		query.Set("offset", strconv.FormatInt(options.Offset, 10))
+	}
+	if options.Cursor != "" {
+		query.Set("cursor", options.Cursor)
+	}
+	if options.Log != "" {
+		query.Set("log", options.Log)
+	}
+	for key, value := range map[string]string{"search": options.Search, "sort": options.Sort, "log_state": options.LogState, "owner": options.Owner} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if len(options.DeviceTypeIds) > 0 {
+		query.Set("device_type_ids", strings.Join(options.DeviceTypeIds, ","))
+	}
+	if len(options.DeviceClassIds) > 0 {
+		query.Set("device_class_ids", strings.Join(options.DeviceClassIds, ","))
+	}
+	for key, value := range options.Attributes {
+		query.Add("attribute", key+":"+value)
+	}
+	if len(options.Fields) > 0 {
+		query.Set("fields", strings.Join(options.Fields, ","))
+	}
+	if options.Expand != nil {
+		query.Set("expand", strings.Join(options.Expand, ","))
+	}
+	return get[model.Page[model.AggregatedDevice]](ctx, this, token, "/v2/devices", query)
+}
+
+// QueryDevices returns the devices with the ids of lookup; ids that can not be read are listed in result.Errors
+func (this *Client) QueryDevices(ctx context.Context, token string, lookup model.DeviceLookup) (result model.DeviceLookupResult, err error, code int) {
+	return post[model.DeviceLookupResult](ctx, this, token, "/devices/query", lookup)
+}
+
+// GetDevice returns the device with the given id; by default with all expansions
+func (this *Client) GetDevice(ctx context.Context, token string, id string, options DeviceOptions) (result model.AggregatedDevice, err error, code int) {
+	query := url.Values{}
+	if options.Log != "" {
+		query.Set("log", options.Log)
+	}
+	if len(options.Fields) > 0 {
+		query.Set("fields", strings.Join(options.Fields, ","))
+	}
+	if options.Expand != nil {
+		query.Set("expand", strings.Join(options.Expand, ","))
+	}
+	return get[model.AggregatedDevice](ctx, this, token, "/devices/"+url.PathEscape(id), query)
+}
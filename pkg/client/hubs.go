@@ -0,0 +1,75 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"net/url"
+	"strconv"
+)
+
+type HubListOptions struct {
+	Limit  int64  //default 100
+	Offset int64  //default 0
+	Cursor string //optional NextCursor or PrevCursor of a previous page; replaces Offset
+	Log    string //optional influxdb duration (e.g. 4h); adds log_history and log_edge
+
+	Search     string //optional; name contains
+	Sort       string //optional <field>.<asc|desc> with field name or log_state
+	LogState   string //optional connected, disconnected or unknown
+	DeviceId   string //optional; hubs containing the device
+	MinDevices int64  //optional minimal count of devices
+	MaxDevices *int64 //optional maximal count of devices
+}
+
+func (this *Client) ListHubs(ctx context.Context, token string, options HubListOptions) (result model.Page[model.AggregatedHub], err error, code int) {
+	query := url.Values{}
+	if options.Limit != 0 {
+		query.Set("limit", strconv.FormatInt(options.Limit, 10))
+	}
+	if options.Offset != 0 {
+		query.Set("offset", strconv.FormatInt(options.Offset, 10))
+	}
+	if options.Cursor != "" {
+		query.Set("cursor", options.Cursor)
+	}
+	if options.Log != "" {
+		query.Set("log", options.Log)
+	}
+	for key, value := range map[string]string{"search": options.Search, "sort": options.Sort, "log_state": options.LogState, "device_id": options.DeviceId} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if options.MinDevices != 0 {
+		query.Set("min_devices", strconv.FormatInt(options.MinDevices, 10))
+	}
+	if options.MaxDevices != nil {
+		query.Set("max_devices", strconv.FormatInt(*options.MaxDevices, 10))
+	}
+	return get[model.Page[model.AggregatedHub]](ctx, this, token, "/v2/hubs", query)
+}
+
+// GetHub returns the hub with the given id and its devices; log is an optional influxdb duration (default 24h)
+func (this *Client) GetHub(ctx context.Context, token string, id string, log string) (result model.AggregatedHub, err error, code int) {
+	query := url.Values{}
+	if log != "" {
+		query.Set("log", log)
+	}
+	return get[model.AggregatedHub](ctx, this, token, "/hubs/"+url.PathEscape(id), query)
+}
@@ -0,0 +1,38 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"net/url"
+)
+
+// ListProcesses forwards query to the camunda-wrapper (see https://docs.camunda.org/manual/7.5/reference/rest/deployment/get-query/)
+func (this *Client) ListProcesses(ctx context.Context, token string, query url.Values) (result []model.AggregatedProcess, err error, code int) {
+	return get[[]model.AggregatedProcess](ctx, this, token, "/v2/processes", query)
+}
+
+// GetDeviceDependents returns the process deployments that depend on the device
+func (this *Client) GetDeviceDependents(ctx context.Context, token string, deviceId string) (result []model.Dependent, err error, code int) {
+	return get[[]model.Dependent](ctx, this, token, "/devices/"+url.PathEscape(deviceId)+"/dependents", nil)
+}
+
+// QueryDependents returns the process deployments that depend on the devices and event filters of lookup
+func (this *Client) QueryDependents(ctx context.Context, token string, lookup model.DependentsLookup) (result model.DependentsLookupResult, err error, code int) {
+	return post[model.DependentsLookupResult](ctx, this, token, "/dependents/query", lookup)
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/auth"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+)
+
+func (this *Lib) GetDeviceClassUses(ctx context.Context, token auth.Token) (result model.DeviceClassUses, err error) {
+	return coalesce(ctx, this, "device-class-uses", token.GetUserId(), func(ctx context.Context) (model.DeviceClassUses, error) {
+		return this.getDeviceClassUses(ctx, token)
+	})
+}
+
+// coalesce lets concurrent calls with the same operation and key share one execution of load (and its upstream requests).
+// key has to contain the user id if the result depends on the token. results are shared and must not be modified by callers.
+// load runs without the cancellation of ctx, so the remaining callers still get a result if the first caller gives up.
+func coalesce[T any](ctx context.Context, this *Lib, operation string, key string, load func(ctx context.Context) (T, error)) (result T, err error) {
+	//singleflight reports the result as shared for the caller executing load too, so only callers not executing load are coalesced
+	executed := false
+	temp, err, _ := this.coalescing.Do(operation+"\n"+key, func() (interface{}, error) {
+		executed = true
+		return load(context.WithoutCancel(ctx))
+	})
+	this.metrics.ObserveCoalescing(operation, !executed)
+	return temp.(T), err
+}
+
+// coalesceWithCode is coalesce for functions that additionally return a status code
+func coalesceWithCode[T any](ctx context.Context, this *Lib, operation string, key string, load func(ctx context.Context) (T, error, int)) (result T, err error, code int) {
+	type withCode struct {
+		value T
+		code  int
+	}
+	temp, err := coalesce(ctx, this, operation, key, func(ctx context.Context) (withCode, error) {
+		value, err, code := load(ctx)
+		return withCode{value: value, code: code}, err
+	})
+	return temp.value, err, temp.code
+}
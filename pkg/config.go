@@ -18,9 +18,11 @@ package pkg
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"net/http"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/events"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/upstream"
+	"log/slog"
 	"os"
 	"reflect"
 	"regexp"
@@ -40,22 +42,80 @@ type Config struct {
 	ProcessDeploymentUrl string `json:"process_deployment_url"`
 	EventManagerUrl      string `json:"event_manager_url"`
 	HttpClientTimeout    string `json:"http_client_timeout"`
+
+	//per upstream timeouts; empty values fall back to HttpClientTimeout
+	IotTimeout               string `json:"iot_timeout"`
+	ImportRepoTimeout        string `json:"import_repo_timeout"`
+	ConnectionLogTimeout     string `json:"connection_log_timeout"`
+	CamundaWrapperTimeout    string `json:"camunda_wrapper_timeout"`
+	ProcessDeploymentTimeout string `json:"process_deployment_timeout"`
+	EventManagerTimeout      string `json:"event_manager_timeout"`
+
+	//retries of idempotent upstream requests; max attempts <= 1 disables retries
+	UpstreamRetryMaxAttempts    int64  `json:"upstream_retry_max_attempts"`
+	UpstreamRetryInitialBackoff string `json:"upstream_retry_initial_backoff"`
+	UpstreamRetryMaxBackoff     string `json:"upstream_retry_max_backoff"`
+
+	//max concurrent upstream calls per aggregation step (e.g. log history and log edges); <= 0 means no limit
+	UpstreamConcurrency int64 `json:"upstream_concurrency"`
+
+	//max ids per upstream request of id-list calls (connection-log, event-manager, process-deployment); <= 0 means no limit
+	IdBatchSize int64 `json:"id_batch_size"`
+
+	//max ids of a device lookup (POST /devices/query); larger requests are rejected with 400; <= 0 means no limit
+	MaxLookupIds int64 `json:"max_lookup_ids"`
+
+	//one circuit breaker per upstream url; a threshold <= 0 disables circuit breaking
+	CircuitBreakerFailureThreshold int64  `json:"circuit_breaker_failure_threshold"`
+	CircuitBreakerOpenTimeout      string `json:"circuit_breaker_open_timeout"`
+
+	//if true, failing enrichment steps (online state, log history, import types, ...) are reported as warnings instead of failing the request
+	PartialResponses bool `json:"partial_responses"`
+
+	//caching of aspects, functions and import types; an empty ttl disables the cache, max entries <= 0 means no size limit
+	AspectCacheTtl     string `json:"aspect_cache_ttl"`
+	FunctionCacheTtl   string `json:"function_cache_ttl"`
+	ImportTypeCacheTtl string `json:"import_type_cache_ttl"`
+	CacheMaxEntries    int64  `json:"cache_max_entries"`
+
+	//optional cache invalidation by the change topics of the device-repository and import-repository;
+	//without kafka_url cache entries expire by their ttl only. topics of "" or "-" are not consumed
+	KafkaUrl           string `json:"kafka_url"`
+	KafkaConsumerGroup string `json:"kafka_consumer_group"`
+	AspectTopic        string `json:"aspect_topic"`
+	FunctionTopic      string `json:"function_topic"`
+	DeviceTypeTopic    string `json:"device_type_topic"`
+	ImportTypeTopic    string `json:"import_type_topic"`
+
+	//log level ("debug", "info", "warn" or "error") and output format ("text" or "json")
+	LogLevel  string `json:"log_level"`
+	LogFormat string `json:"log_format"`
+
+	//tracing exporter: "" (disabled), "stdout", "file" (writes to TracingFile) or "otlp" (otlp/http to TracingOtlpEndpoint)
+	TracingExporter     string `json:"tracing_exporter"`
+	TracingFile         string `json:"tracing_file"`
+	TracingOtlpEndpoint string `json:"tracing_otlp_endpoint"`
+	TracingServiceName  string `json:"tracing_service_name"`
+
+	//record-and-replay of upstream traffic: RecordUpstreams writes every upstream exchange to the given file,
+	//ReplayUpstreams answers all upstream requests from such a file instead of the network ("demo" mode)
+	RecordUpstreams string `json:"record_upstreams"`
+	ReplayUpstreams string `json:"replay_upstreams"`
 }
 
 func LoadConfig(location string) (config Config, err error) {
 	file, err := os.Open(location)
 	if err != nil {
-		log.Println("error on config load: ", err)
+		slog.Error("unable to load config", "location", location, "error", err)
 		return config, err
 	}
 	decoder := json.NewDecoder(file)
 	err = decoder.Decode(&config)
 	if err != nil {
-		log.Println("invalid config json: ", err)
+		slog.Error("invalid config json", "location", location, "error", err)
 		return config, err
 	}
 	handleEnvironmentVars(&config)
-	setDefaultHttpClient(config)
 	return config, nil
 }
 
@@ -120,10 +180,85 @@ func handleEnvironmentVars(config *Config) {
 	}
 }
 
-func setDefaultHttpClient(config Config) {
-	var err error
-	http.DefaultClient.Timeout, err = time.ParseDuration(config.HttpClientTimeout)
+// UpstreamTimeout parses the timeout of an upstream, falling back to HttpClientTimeout if timeout is empty
+func (this Config) UpstreamTimeout(timeout string) time.Duration {
+	if timeout == "" {
+		timeout = this.HttpClientTimeout
+	}
+	if timeout == "" {
+		return 0
+	}
+	result, err := time.ParseDuration(timeout)
+	if err != nil {
+		slog.Warn("invalid http timeout --> no timeouts", "error", err)
+		return 0
+	}
+	return result
+}
+
+func (this Config) UpstreamRetryPolicy() upstream.RetryPolicy {
+	return upstream.RetryPolicy{
+		MaxAttempts:    int(this.UpstreamRetryMaxAttempts),
+		InitialBackoff: parseDurationOrZero("upstream_retry_initial_backoff", this.UpstreamRetryInitialBackoff),
+		MaxBackoff:     parseDurationOrZero("upstream_retry_max_backoff", this.UpstreamRetryMaxBackoff),
+	}
+}
+
+// UpstreamMiddleware returns the upstream.Middleware for RecordUpstreams or ReplayUpstreams (nil if neither is set).
+// in replay mode no upstream has to be reachable, so unset upstream urls of the returned config are replaced by placeholders.
+// the returned shutdown function closes the recording file.
+func (this Config) UpstreamMiddleware() (config Config, middleware upstream.Middleware, shutdown func() error, err error) {
+	noop := func() error { return nil }
+	switch {
+	case this.RecordUpstreams != "" && this.ReplayUpstreams != "":
+		return this, nil, noop, errors.New("record_upstreams and replay_upstreams may not be used together")
+	case this.RecordUpstreams != "":
+		recorder, err := upstream.NewRecorder(this.RecordUpstreams)
+		if err != nil {
+			return this, nil, noop, err
+		}
+		slog.Warn("recording upstream exchanges", "file", this.RecordUpstreams)
+		return this, recorder.Middleware, recorder.Close, nil
+	case this.ReplayUpstreams != "":
+		recording, err := upstream.LoadRecording(this.ReplayUpstreams)
+		if err != nil {
+			return this, nil, noop, err
+		}
+		for name, url := range map[string]*string{
+			UpstreamIot:               &this.IotUrl,
+			UpstreamImportRepo:        &this.ImportRepoUrl,
+			UpstreamConnectionLog:     &this.ConnectionLogUrl,
+			UpstreamCamundaWrapper:    &this.CamundaWrapperUrl,
+			UpstreamProcessDeployment: &this.ProcessDeploymentUrl,
+			UpstreamEventManager:      &this.EventManagerUrl,
+		} {
+			if *url == "" || *url == "-" {
+				*url = "http://" + name + ".replay"
+			}
+		}
+		slog.Warn("replaying recorded upstream exchanges", "file", this.ReplayUpstreams, "exchanges", len(recording.Exchanges))
+		return this, upstream.NewReplayer(recording).Middleware, noop, nil
+	default:
+		return this, nil, noop, nil
+	}
+}
+
+// CacheInvalidationBroker returns the broker for Lib.StartCacheInvalidation; nil if no kafka_url is configured
+func (this Config) CacheInvalidationBroker() events.Broker {
+	if this.KafkaUrl == "" || this.KafkaUrl == "-" {
+		return nil
+	}
+	return events.NewKafka(this.KafkaUrl, this.KafkaConsumerGroup)
+}
+
+func parseDurationOrZero(field string, value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	result, err := time.ParseDuration(value)
 	if err != nil {
-		log.Println("WARNING: invalid http timeout --> no timeouts\n", err)
+		slog.Warn("invalid duration --> use 0", "field", field, "error", err)
+		return 0
 	}
+	return result
 }
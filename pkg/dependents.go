@@ -0,0 +1,87 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/auth"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"net/url"
+)
+
+// GetDependents returns the process deployments of the user that reference the given devices or event filters,
+// with the bpmn tasks using them. every requested id is contained in the result, ids without dependents map to empty lists.
+func (this *Lib) GetDependents(ctx context.Context, token auth.Token, deviceIds []string, eventIds []string) (result model.DependentsLookupResult, err error) {
+	result = model.DependentsLookupResult{Devices: map[string][]model.Dependent{}, Events: map[string][]model.Dependent{}}
+	for _, id := range deviceIds {
+		result.Devices[id] = []model.Dependent{}
+	}
+	for _, id := range eventIds {
+		result.Events[id] = []model.Dependent{}
+	}
+	if len(deviceIds) == 0 && len(eventIds) == 0 {
+		return result, nil
+	}
+	dependencies, names, err := this.getDeploymentDependencies(ctx, token)
+	if err != nil {
+		return result, err
+	}
+	for _, dependency := range dependencies {
+		for _, device := range dependency.Devices {
+			if dependents, ok := result.Devices[device.DeviceId]; ok {
+				result.Devices[device.DeviceId] = addDependent(dependents, dependency.DeploymentId, names[dependency.DeploymentId], device.BpmnResources)
+			}
+		}
+		for _, event := range dependency.Events {
+			if dependents, ok := result.Events[event.EventId]; ok {
+				result.Events[event.EventId] = addDependent(dependents, dependency.DeploymentId, names[dependency.DeploymentId], event.BpmnResources)
+			}
+		}
+	}
+	return result, nil
+}
+
+// addDependent adds the tasks of a deployment to dependents; a deployment referencing an id multiple times is listed once
+func addDependent(dependents []model.Dependent, deploymentId string, name string, resources []BpmnResource) []model.Dependent {
+	tasks := []model.BpmnTask{}
+	for _, resource := range resources {
+		tasks = append(tasks, model.BpmnTask{Id: resource.Id, Label: resource.Label})
+	}
+	for i, dependent := range dependents {
+		if dependent.Id == deploymentId {
+			dependents[i].Tasks = append(dependent.Tasks, tasks...)
+			return dependents
+		}
+	}
+	return append(dependents, model.Dependent{Id: deploymentId, Name: name, Tasks: tasks})
+}
+
+// getDeploymentDependencies returns the dependencies of the process deployments of the user and the deployment names by id
+func (this *Lib) getDeploymentDependencies(ctx context.Context, token auth.Token) (dependencies []Dependencies, names map[string]string, err error) {
+	deployments, err := this.GetProcessDeploymentList(ctx, token, url.Values{})
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := []string{}
+	names = map[string]string{}
+	for _, deployment := range deployments {
+		ids = append(ids, deployment.Id)
+		names[deployment.Id] = deployment.Name
+	}
+	dependencies, err = this.GetProcessDependencyList(ctx, token, ids)
+	return dependencies, names, err
+}
@@ -0,0 +1,233 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/auth"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/models/go/models"
+	"slices"
+)
+
+// device expansions as used by the expand query parameter
+const (
+	ExpandDeviceType         = "device_type"
+	ExpandDeviceClass        = "device_class"
+	ExpandHub                = "hub"
+	ExpandLogHistory         = "log_history"
+	ExpandLogEdge            = "log_edge"
+	ExpandLogStart           = "log_start"
+	ExpandDependentProcesses = "dependent_processes"
+)
+
+var DeviceExpansionNames = []string{ExpandDeviceType, ExpandDeviceClass, ExpandHub, ExpandLogHistory, ExpandLogEdge, ExpandLogStart, ExpandDependentProcesses}
+
+// DeviceExpansions selects the optional enrichments of ExpandDevices
+type DeviceExpansions struct {
+	DeviceType         bool
+	DeviceClass        bool
+	Hubs               bool
+	LogHistory         bool
+	LogEdge            bool
+	LogStart           bool
+	DependentProcesses bool
+	LogDuration        string //influxdb duration of LogHistory and LogEdge
+}
+
+// ParseDeviceExpansions returns the DeviceExpansions of a list of expansion names (see DeviceExpansionNames)
+func ParseDeviceExpansions(names []string, logDuration string) (result DeviceExpansions, err error) {
+	result.LogDuration = logDuration
+	for _, name := range names {
+		switch name {
+		case ExpandDeviceType:
+			result.DeviceType = true
+		case ExpandDeviceClass:
+			result.DeviceClass = true
+		case ExpandHub:
+			result.Hubs = true
+		case ExpandLogHistory:
+			result.LogHistory = true
+		case ExpandLogEdge:
+			result.LogEdge = true
+		case ExpandLogStart:
+			result.LogStart = true
+		case ExpandDependentProcesses:
+			result.DependentProcesses = true
+		default:
+			return result, fmt.Errorf("unknown expansion %v", name)
+		}
+	}
+	if (result.LogHistory || result.LogEdge) && logDuration == "" {
+		return result, errors.New("log_history and log_edge expansions need a log duration")
+	}
+	return result, nil
+}
+
+// NeedsDeviceType returns true if the device types have to be requested from the device-repository
+func (this DeviceExpansions) NeedsDeviceType() bool {
+	return this.DeviceType || this.DeviceClass
+}
+
+// ExpandDevices adds the requested expansions to devices found by FindDevices. the enrichments are requested concurrently;
+// devices have to contain their device type if DeviceClass is requested, which is removed if DeviceType is not requested.
+func (this *Lib) ExpandDevices(ctx context.Context, token auth.Token, expansions DeviceExpansions, devices []model.AggregatedDevice) (result []model.AggregatedDevice, err error) {
+	var logged []model.AggregatedDevice
+	var deviceClasses map[string]models.DeviceClass
+	var logStarts map[string]interface{}
+	var hubs map[string][]model.HubReference
+	var processes map[string][]model.ProcessReference
+	group, groupCtx := this.newGroup(ctx)
+	if expansions.LogHistory || expansions.LogEdge {
+		group.Go(func() (err error) {
+			logged, err = this.completeDeviceLog(groupCtx, token, expansions.LogDuration, devices, expansions.LogHistory, expansions.LogEdge)
+			return err
+		})
+	}
+	if expansions.LogStart {
+		group.Go(func() (err error) {
+			logStarts, err = this.GetLogstarts(groupCtx, token, "device", deviceIds(devices))
+			if err != nil && !Degrade(groupCtx, this.config, UpstreamConnectionLog, StepLogStart, err) {
+				return err
+			}
+			return nil
+		})
+	}
+	if expansions.DeviceClass {
+		group.Go(func() (err error) {
+			deviceClasses, err = this.getDeviceClasses(groupCtx, devices)
+			if err != nil && !Degrade(groupCtx, this.config, UpstreamIot, StepDeviceClasses, err) {
+				return err
+			}
+			return nil
+		})
+	}
+	if expansions.Hubs {
+		group.Go(func() (err error) {
+			hubs, err = this.getDeviceHubs(groupCtx, token)
+			if err != nil && !Degrade(groupCtx, this.config, UpstreamIot, StepHubs, err) {
+				return err
+			}
+			return nil
+		})
+	}
+	if expansions.DependentProcesses {
+		group.Go(func() (err error) {
+			processes, err = this.getDependentProcesses(groupCtx, token)
+			if err != nil && !Degrade(groupCtx, this.config, UpstreamProcessDeployment, StepDependencies, err) {
+				return err
+			}
+			return nil
+		})
+	}
+	err = group.Wait()
+	if err != nil {
+		return nil, err
+	}
+	result = make([]model.AggregatedDevice, 0, len(devices))
+	for i, device := range devices {
+		if logged != nil {
+			device.LogHistory, device.LogEdge = logged[i].LogHistory, logged[i].LogEdge
+		}
+		if device.DeviceType != nil {
+			if deviceClass, ok := deviceClasses[device.DeviceType.DeviceClassId]; ok {
+				device.DeviceClass = &deviceClass
+			}
+		}
+		device.LogStart = logStarts[device.Id]
+		device.Hubs = hubs[device.Id]
+		device.DependentProcesses = processes[device.Id]
+		if !expansions.DeviceType {
+			device.DeviceType = nil
+		}
+		result = append(result, device)
+	}
+	return result, nil
+}
+
+func deviceIds(devices []model.AggregatedDevice) (ids []string) {
+	ids = []string{}
+	for _, device := range devices {
+		ids = append(ids, device.Id)
+	}
+	return ids
+}
+
+// getDeviceClasses returns the device classes of the device types of devices by id
+func (this *Lib) getDeviceClasses(ctx context.Context, devices []model.AggregatedDevice) (result map[string]models.DeviceClass, err error) {
+	ids := []string{}
+	for _, device := range devices {
+		if device.DeviceType != nil && device.DeviceType.DeviceClassId != "" && !slices.Contains(ids, device.DeviceType.DeviceClassId) {
+			ids = append(ids, device.DeviceType.DeviceClassId)
+		}
+	}
+	result = map[string]models.DeviceClass{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+	deviceClasses, err := batchList(ctx, this, ids, func(ctx context.Context, ids []string) (result []models.DeviceClass, err error) {
+		result, _, err, _ = this.deviceRepo.ListDeviceClasses(ctx, client.DeviceClassListOptions{
+			Ids:    ids,
+			Limit:  int64(len(ids)),
+			SortBy: "name.asc",
+		})
+		return result, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, deviceClass := range deviceClasses {
+		result[deviceClass.Id] = deviceClass
+	}
+	return result, nil
+}
+
+// getDeviceHubs returns the hubs of the user by device id
+func (this *Lib) getDeviceHubs(ctx context.Context, token auth.Token) (result map[string][]model.HubReference, err error) {
+	hubs, err := this.ListAllGateways(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	result = map[string][]model.HubReference{}
+	for _, hub := range hubs {
+		for _, deviceId := range hub.DeviceIds {
+			result[deviceId] = append(result[deviceId], model.HubReference{Id: hub.Id, Name: hub.Name})
+		}
+	}
+	return result, nil
+}
+
+// getDependentProcesses returns the process deployments of the user by the ids of the devices they depend on
+func (this *Lib) getDependentProcesses(ctx context.Context, token auth.Token) (result map[string][]model.ProcessReference, err error) {
+	dependencies, names, err := this.getDeploymentDependencies(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	result = map[string][]model.ProcessReference{}
+	for _, dependency := range dependencies {
+		for _, device := range dependency.Devices {
+			reference := model.ProcessReference{Id: dependency.DeploymentId, Name: names[dependency.DeploymentId]}
+			if !slices.Contains(result[device.DeviceId], reference) {
+				result[device.DeviceId] = append(result[device.DeviceId], reference)
+			}
+		}
+	}
+	return result, nil
+}
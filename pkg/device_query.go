@@ -0,0 +1,116 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/models/go/models"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// DeviceQuery filters and sorts the devices of FindDevices.
+// search, sort, device types, log states and owner are applied by the device-repository, the other filters by the aggregator.
+type DeviceQuery struct {
+	Search         string            //name or display name contains (case-insensitive)
+	SortBy         string            //<field>.<asc|desc> with a field of DeviceSortFields; defaults to name.asc
+	DeviceTypeIds  []string          //ignored if empty
+	DeviceClassIds []string          //ignored if empty
+	Attributes     map[string]string //attribute key -> value; all have to match
+	LogState       string            //model.LogStateConnected, model.LogStateDisconnected or model.LogStateUnknown
+	Owner          string            //owner id
+	SkipDeviceType bool              //don't request the device types (DeviceType is nil), unless DeviceClassIds are set
+}
+
+// DeviceSortFields maps the sort fields of DeviceQuery.SortBy to the fields of the device-repository
+var DeviceSortFields = map[string]string{
+	"name":         "name",
+	"display_name": "display_name",
+	"log_state":    "connection_state",
+}
+
+var logStateToConnectionState = map[string]models.ConnectionState{
+	model.LogStateConnected:    models.ConnectionStateOnline,
+	model.LogStateDisconnected: models.ConnectionStateOffline,
+	model.LogStateUnknown:      models.ConnectionStateUnknown,
+}
+
+// Validate returns an error if SortBy or LogState are unknown
+func (this DeviceQuery) Validate() error {
+	if this.SortBy != "" {
+		field, direction, _ := strings.Cut(this.SortBy, ".")
+		if _, ok := DeviceSortFields[field]; !ok || (direction != "asc" && direction != "desc") {
+			return errors.New("unknown sort, expected <field>.<asc|desc> with field in " + strings.Join(slices.Sorted(maps.Keys(DeviceSortFields)), ", "))
+		}
+	}
+	if _, ok := logStateToConnectionState[this.LogState]; this.LogState != "" && !ok {
+		return errors.New("unknown log_state, expected connected, disconnected or unknown")
+	}
+	return nil
+}
+
+func (this DeviceQuery) listOptions() client.ExtendedDeviceListOptions {
+	options := client.ExtendedDeviceListOptions{
+		Search:     this.Search,
+		SortBy:     "name.asc",
+		Permission: client.READ,
+		FullDt:     !this.SkipDeviceType || len(this.DeviceClassIds) > 0,
+	}
+	if this.SortBy != "" {
+		field, direction, _ := strings.Cut(this.SortBy, ".")
+		options.SortBy = DeviceSortFields[field] + "." + direction
+	}
+	if len(this.DeviceTypeIds) > 0 {
+		options.DeviceTypeIds = this.DeviceTypeIds
+	}
+	options.Owner = this.Owner
+	if state, ok := logStateToConnectionState[this.LogState]; ok && this.LogState != model.LogStateUnknown {
+		options.ConnectionState = &state
+	}
+	//the device-repository evaluates keys and values independently, which only narrows the result
+	for _, key := range slices.Sorted(maps.Keys(this.Attributes)) {
+		options.AttributeKeys = append(options.AttributeKeys, key)
+		options.AttributeValues = append(options.AttributeValues, this.Attributes[key])
+	}
+	return options
+}
+
+// filteredLocally returns true if the query contains filters the device-repository can not apply
+func (this DeviceQuery) filteredLocally() bool {
+	return len(this.DeviceClassIds) > 0 || len(this.Attributes) > 0 || this.LogState == model.LogStateUnknown
+}
+
+// matches applies the filters of filteredLocally to a device of the device-repository (requested with full device-type)
+func (this DeviceQuery) matches(device models.ExtendedDevice) bool {
+	if len(this.DeviceClassIds) > 0 && (device.DeviceType == nil || !slices.Contains(this.DeviceClassIds, device.DeviceType.DeviceClassId)) {
+		return false
+	}
+	if this.LogState == model.LogStateUnknown && logState(device.ConnectionState) != model.LogStateUnknown {
+		return false
+	}
+	for key, value := range this.Attributes {
+		if !slices.ContainsFunc(device.Attributes, func(attribute models.Attribute) bool {
+			return attribute.Key == key && attribute.Value == value
+		}) {
+			return false
+		}
+	}
+	return true
+}
@@ -17,21 +17,23 @@
 package pkg
 
 import (
+	"context"
 	"github.com/SENERGY-Platform/api-aggregator/pkg/auth"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
 	"github.com/SENERGY-Platform/device-repository/lib/client"
 	"github.com/SENERGY-Platform/models/go/models"
 	"maps"
 	"slices"
 )
 
-func (this *Lib) GetDeviceClassUses(token auth.Token) (result interface{}, err error) {
+func (this *Lib) getDeviceClassUses(ctx context.Context, token auth.Token) (result model.DeviceClassUses, err error) {
 	allDevices := []models.ExtendedDevice{}
 	deviceClassToDevices := map[string][]string{}
 	deviceTypeToDevice := map[string][]string{}
 	for {
 		var limit int64 = 9999
 		var offset int64 = 0
-		devices, _, err, _ := this.deviceRepo.ListExtendedDevices(token.Jwt(), client.ExtendedDeviceListOptions{
+		devices, _, err, _ := this.deviceRepo.ListExtendedDevices(ctx, token.Jwt(), client.ExtendedDeviceListOptions{
 			Limit:      limit,
 			Offset:     offset,
 			SortBy:     "name.asc",
@@ -51,11 +53,11 @@ func (this *Lib) GetDeviceClassUses(token auth.Token) (result interface{}, err e
 		}
 		offset = offset + limit
 	}
-	deviceClassIds := slices.Collect(maps.Keys(deviceClassToDevices))
+	deviceClassIds := slices.Sorted(maps.Keys(deviceClassToDevices))
 	if deviceClassIds == nil {
 		deviceClassIds = []string{}
 	}
-	deviceClasses, _, err, _ := this.deviceRepo.ListDeviceClasses(client.DeviceClassListOptions{
+	deviceClasses, _, err, _ := this.deviceRepo.ListDeviceClasses(ctx, client.DeviceClassListOptions{
 		Ids:    deviceClassIds,
 		Limit:  int64(len(deviceClassIds)),
 		Offset: 0,
@@ -65,5 +67,5 @@ func (this *Lib) GetDeviceClassUses(token auth.Token) (result interface{}, err e
 		return result, err
 	}
 
-	return map[string]interface{}{"device-classes": deviceClasses, "used-devices": deviceClassToDevices}, nil
+	return model.DeviceClassUses{DeviceClasses: deviceClasses, UsedDevices: deviceClassToDevices}, nil
 }
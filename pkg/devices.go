@@ -17,87 +17,214 @@
 package pkg
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"github.com/SENERGY-Platform/api-aggregator/pkg/auth"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/upstream"
 	"github.com/SENERGY-Platform/device-repository/lib/client"
 	"github.com/SENERGY-Platform/models/go/models"
-	"log"
+	"net/http"
+	"slices"
 )
 
-func (this *Lib) FindDevices(token auth.Token, limit int, offset int) (devices []map[string]interface{}, err error) {
-	devicesFromRepo, _, err, _ := this.deviceRepo.ListExtendedDevices(token.Jwt(), client.ExtendedDeviceListOptions{
-		Limit:      int64(limit),
-		Offset:     int64(offset),
-		SortBy:     "name.asc",
-		Permission: client.READ,
-		FullDt:     true,
-	})
+// FindDevices returns a page of the devices of the user matching query and the total count of matching devices
+func (this *Lib) FindDevices(ctx context.Context, token auth.Token, query DeviceQuery, limit int64, offset int64) (devices []model.AggregatedDevice, total int64, err error) {
+	list := this.deviceLister(ctx, token, query)
+	if !query.filteredLocally() {
+		devicesFromRepo, total, err := list(limit, offset)
+		if err != nil {
+			return nil, total, err
+		}
+		return aggregateDevices(devicesFromRepo), total, nil
+	}
+	//the page and the total count depend on filters of the aggregator, so all devices of the device-repository query are needed
+	devicesFromRepo, err := listAll(list)
 	if err != nil {
-		return nil, err
+		return nil, total, err
+	}
+	devicesFromRepo = slices.DeleteFunc(devicesFromRepo, func(device models.ExtendedDevice) bool { return !query.matches(device) })
+	devicesFromRepo, total = page(devicesFromRepo, limit, offset)
+	return aggregateDevices(devicesFromRepo), total, nil
+}
+
+// deviceLister returns a function listing the devices of the device-repository query of a DeviceQuery
+func (this *Lib) deviceLister(ctx context.Context, token auth.Token, query DeviceQuery) func(limit int64, offset int64) ([]models.ExtendedDevice, int64, error) {
+	return func(limit int64, offset int64) ([]models.ExtendedDevice, int64, error) {
+		options := query.listOptions()
+		options.Limit, options.Offset = limit, offset
+		result, total, err, _ := this.deviceRepo.ListExtendedDevices(ctx, token.Jwt(), options)
+		return result, total, err
 	}
-	return this.legacyDeviceTransformations(token, devicesFromRepo)
 }
 
-func (this *Lib) legacyDeviceTransformations(token auth.Token, devices []models.ExtendedDevice) (result []map[string]interface{}, err error) {
+func aggregateDevices(devices []models.ExtendedDevice) (result []model.AggregatedDevice) {
+	result = []model.AggregatedDevice{}
 	for _, device := range devices {
-		element := map[string]interface{}{}
-		temp, err := json.Marshal(device)
-		if err != nil {
-			return nil, err
-		}
-		err = json.Unmarshal(temp, &element)
-		if err != nil {
-			return nil, err
-		}
-		switch device.ConnectionState {
-		case *client.ConnectionStateOnline:
-			element["log_state"] = "connected"
-		case *client.ConnectionStateOffline:
-			element["log_state"] = "disconnected"
-		case *client.ConnectionStateUnknown:
-			element["log_state"] = "unknown"
-		default:
-			element["log_state"] = "unknown"
-		}
-		element["creator"] = device.OwnerId
-		result = append(result, element)
+		result = append(result, model.AggregatedDevice{
+			ExtendedDevice: device,
+			LogState:       logState(device.ConnectionState),
+			Creator:        device.OwnerId,
+		})
 	}
-	return
+	return result
 }
 
-func (this *Lib) CompleteDeviceHistory(token auth.Token, duration string, devices []map[string]interface{}) (result []map[string]interface{}, err error) {
+func logState(state models.ConnectionState) string {
+	switch state {
+	case models.ConnectionStateOnline:
+		return model.LogStateConnected
+	case models.ConnectionStateOffline:
+		return model.LogStateDisconnected
+	default:
+		return model.LogStateUnknown
+	}
+}
+
+func (this *Lib) CompleteDeviceHistory(ctx context.Context, token auth.Token, duration string, devices []model.AggregatedDevice) (result []model.AggregatedDevice, err error) {
+	return this.completeDeviceLog(ctx, token, duration, devices, true, true)
+}
+
+// completeDeviceLog sets log_history and/or log_edge of devices
+func (this *Lib) completeDeviceLog(ctx context.Context, token auth.Token, duration string, devices []model.AggregatedDevice, withHistory bool, withEdges bool) (result []model.AggregatedDevice, err error) {
 	ids := []string{}
-	deviceMap := map[string]map[string]interface{}{}
 	for _, device := range devices {
-		id, ok := device["id"]
-		if !ok {
-			err = errors.New("unable to get device id")
-			return
+		ids = append(ids, device.Id)
+	}
+	//on partial responses unavailable log_history and log_edge values are omitted
+	historyAvailable, edgesAvailable := withHistory, withEdges
+	var logHistory map[string]model.HistorySeries
+	var logEdges map[string]interface{}
+	group, groupCtx := this.newGroup(ctx)
+	if withHistory {
+		group.Go(func() (err error) {
+			logHistory, err = this.GetDeviceLogHistory(groupCtx, token, ids, duration)
+			if err != nil {
+				if !Degrade(groupCtx, this.config, UpstreamConnectionLog, StepLogHistory, err) {
+					return err
+				}
+				historyAvailable = false
+			}
+			return nil
+		})
+	}
+	if withEdges {
+		group.Go(func() (err error) {
+			logEdges, err = this.GetLogedges(groupCtx, token, "device", ids, duration)
+			if err != nil {
+				if !Degrade(groupCtx, this.config, UpstreamConnectionLog, StepLogEdge, err) {
+					return err
+				}
+				edgesAvailable = false
+			}
+			return nil
+		})
+	}
+	err = group.Wait()
+	if err != nil {
+		return result, err
+	}
+	result = make([]model.AggregatedDevice, 0, len(devices))
+	for _, device := range devices {
+		if historyAvailable {
+			history := logHistory[device.Id]
+			device.LogHistory = &history
+		}
+		if edgesAvailable {
+			device.LogEdge = logEdges[device.Id]
 		}
-		idStr, ok := id.(string)
-		if !ok {
-			err = errors.New("unable to cast device id to string")
-			return
+		result = append(result, device)
+	}
+	return result, nil
+}
+
+// LookupDevices returns the devices with the given ids in the order of ids. ids that are not found by the id-filtered
+// device list are read individually to report their status (e.g. 404 or 403) as model.DeviceError.
+func (this *Lib) LookupDevices(ctx context.Context, token auth.Token, ids []string, fullDt bool) (devices []model.AggregatedDevice, deviceErrors []model.DeviceError, err error) {
+	unique := []string{}
+	index := map[string]models.ExtendedDevice{}
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
 		}
-		ids = append(ids, idStr)
-		deviceMap[idStr] = device
 	}
-	logHistory, err := this.GetDeviceLogHistory(token, ids, duration)
-	if err != nil {
-		log.Println("ERROR legacyDeviceTransformations.GetDeviceLogHistory()", err)
+	found, err := batchList(ctx, this, unique, func(ctx context.Context, ids []string) (result []models.ExtendedDevice, err error) {
+		result, _, err, _ = this.deviceRepo.ListExtendedDevices(ctx, token.Jwt(), client.ExtendedDeviceListOptions{
+			Ids:        ids,
+			Limit:      int64(len(ids)),
+			SortBy:     "name.asc",
+			Permission: client.READ,
+			FullDt:     fullDt,
+		})
 		return result, err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, device := range found {
+		index[device.Id] = device
+	}
+	missing := slices.DeleteFunc(slices.Clone(unique), func(id string) bool {
+		_, ok := index[id]
+		return ok
+	})
+	read := make([]models.ExtendedDevice, len(missing))
+	readErrors := make([]*model.DeviceError, len(missing))
+	group, groupCtx := this.newGroup(ctx)
+	for i, id := range missing {
+		group.Go(func() error {
+			device, err, code := this.deviceRepo.ReadExtendedDevice(groupCtx, token.Jwt(), id, client.READ, fullDt)
+			var responseErr *upstream.ResponseError
+			switch {
+			case err == nil:
+				read[i] = device
+			case code >= 400 && code < 500 && errors.As(err, &responseErr):
+				readErrors[i] = &model.DeviceError{Id: id, StatusCode: code, Message: responseErr.Message}
+			default:
+				return err
+			}
+			return nil
+		})
 	}
-	logEdges, err := this.GetLogedges(token, "device", ids, duration)
+	err = group.Wait()
 	if err != nil {
-		log.Println("ERROR legacyDeviceTransformations.GetLogedges()", err)
-		return result, err
+		return nil, nil, err
 	}
-	for _, id := range ids {
-		device := deviceMap[id]
-		device["log_history"] = logHistory[id]
-		device["log_edge"] = logEdges[id]
-		result = append(result, device)
+	deviceErrors = []model.DeviceError{}
+	for i, id := range missing {
+		if readErrors[i] != nil {
+			deviceErrors = append(deviceErrors, *readErrors[i])
+		} else {
+			index[id] = read[i]
+		}
+	}
+	ordered := []models.ExtendedDevice{}
+	for _, id := range unique {
+		if device, ok := index[id]; ok {
+			ordered = append(ordered, device)
+		}
+	}
+	return aggregateDevices(ordered), deviceErrors, nil
+}
+
+// GetDevice returns the device with the given id and the requested expansions.
+// the returned code is the status of the device-repository if the device can not be read (e.g. 404 or 403).
+func (this *Lib) GetDevice(ctx context.Context, token auth.Token, id string, expansions DeviceExpansions) (device model.AggregatedDevice, err error, code int) {
+	devices, deviceErrors, err := this.LookupDevices(ctx, token, []string{id}, expansions.NeedsDeviceType())
+	if err != nil {
+		return device, err, http.StatusInternalServerError
+	}
+	if len(deviceErrors) > 0 {
+		return device, errors.New(deviceErrors[0].Message), deviceErrors[0].StatusCode
+	}
+	if len(devices) == 0 {
+		return device, errors.New("not found"), http.StatusNotFound
+	}
+	devices, err = this.ExpandDevices(ctx, token, expansions, devices)
+	if err != nil {
+		return device, err, http.StatusInternalServerError
 	}
-	return
+	return devices[0], nil, http.StatusOK
 }
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package events consumes change notifications of other platform services (e.g. the "aspects" topic of the device-repository).
+package events
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Broker delivers the messages of a topic to handler until ctx is done
+type Broker interface {
+	Subscribe(ctx context.Context, topic string, handler func(message []byte)) error
+}
+
+type Kafka struct {
+	url           string
+	consumerGroup string
+}
+
+// NewKafka creates a Broker for the kafka cluster reachable at url.
+// every instance of the aggregator has to see every message, so each Subscribe uses its own consumer group
+// (consumerGroup with a random suffix) that starts at the newest offset.
+func NewKafka(url string, consumerGroup string) *Kafka {
+	return &Kafka{url: url, consumerGroup: consumerGroup}
+}
+
+func (this *Kafka) Subscribe(ctx context.Context, topic string, handler func(message []byte)) error {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     []string{this.url},
+		GroupID:     this.consumerGroup + "-" + uuid.NewString(),
+		Topic:       topic,
+		StartOffset: kafka.LastOffset,
+		MaxWait:     time.Second,
+	})
+	go func() {
+		defer reader.Close()
+		for {
+			message, err := reader.ReadMessage(ctx)
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				slog.Error("unable to consume kafka topic", "topic", topic, "error", err)
+				time.Sleep(time.Second)
+				continue
+			}
+			handler(message.Value)
+		}
+	}()
+	return nil
+}
@@ -17,44 +17,38 @@
 package pkg
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
-	"log"
-	"net/http"
+	"log/slog"
 	"net/url"
-	"runtime/debug"
 	"strings"
 )
 
-func (this *Lib) CheckEventStates(token string, ids []string) (result map[string]bool, err error) {
-	result = map[string]bool{}
-	if this.config.EventManagerUrl == "" || this.config.EventManagerUrl == "-" {
-		return result, nil
-	}
-	req, err := http.NewRequest("GET", this.config.EventManagerUrl+"/event-states?ids="+url.QueryEscape(strings.Join(ids, ",")), nil)
-	if err != nil {
-		debug.PrintStack()
-		return result, err
+func (this *Lib) CheckEventStates(ctx context.Context, token string, ids []string) (result map[string]bool, err error) {
+	if !this.eventManager.Enabled() {
+		return map[string]bool{}, nil
 	}
-	req.Header.Set("Authorization", token)
-	resp, err := http.DefaultClient.Do(req)
+	return batchMap(ctx, this, ids, func(ctx context.Context, ids []string) (map[string]bool, error) {
+		return this.checkEventStates(ctx, token, ids)
+	})
+}
+
+func (this *Lib) checkEventStates(ctx context.Context, token string, ids []string) (result map[string]bool, err error) {
+	result = map[string]bool{}
+	resp, err := this.eventManager.Get(ctx, token, "/event-states?ids="+url.QueryEscape(strings.Join(ids, ",")))
 	if err != nil {
-		log.Println("ERROR: GetProcessDeploymentList()::http.DefaultClient.Do(req)", err)
-		debug.PrintStack()
 		return result, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 500 {
 		responseMsg, _ := ioutil.ReadAll(resp.Body)
-		log.Println("ERROR: CheckEventState(): unexpected response", resp.StatusCode, string(responseMsg))
-		debug.PrintStack()
 		return result, errors.New(string(responseMsg))
 	}
 	if resp.StatusCode != 200 {
 		responseMsg, _ := ioutil.ReadAll(resp.Body)
-		log.Println("DEBUG: event pipeline not ready:", resp.StatusCode, string(responseMsg))
-		debug.PrintStack()
+		slog.DebugContext(ctx, "event pipeline not ready", "upstream", UpstreamEventManager, "status_code", resp.StatusCode, "response", string(responseMsg))
 		return result, nil
 	}
 	err = json.NewDecoder(resp.Body).Decode(&result)
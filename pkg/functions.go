@@ -17,6 +17,7 @@
 package pkg
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/SENERGY-Platform/api-aggregator/pkg/auth"
@@ -26,19 +27,12 @@ import (
 	"net/url"
 )
 
-type Function struct {
-	Id          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	ConceptId   string `json:"concept_id"`
-	RdfType     string `json:"rdf_type"`
-}
-
-func (this *Lib) GetMeasuringFunctionsForAspect(token auth.Token, aspectId string) (functions []Function, err error, code int) {
-	resp, err := get(token.Token, this.config.IotUrl+"/aspects/"+url.PathEscape(aspectId)+"/measuring-functions")
+func (this *Lib) getMeasuringFunctionsForAspect(ctx context.Context, token auth.Token, aspectId string) (functions []model.MeasuringFunction, err error, code int) {
+	resp, err := this.iot.Get(ctx, token.Token, "/aspects/"+url.PathEscape(aspectId)+"/measuring-functions")
 	if err != nil {
 		return nil, err, http.StatusBadGateway
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode > 299 {
 		return nil, errors.New("unexpected status code from semantic-repo"), resp.StatusCode
 	}
@@ -46,8 +40,8 @@ func (this *Lib) GetMeasuringFunctionsForAspect(token auth.Token, aspectId strin
 	return functions, err, resp.StatusCode
 }
 
-func (this *Lib) GetMeasuringFunctions(token auth.Token, functionIds []string) (functions []Function, err error, code int) {
-	temp, _, err, _ := this.deviceRepo.ListFunctions(client.FunctionListOptions{
+func (this *Lib) getMeasuringFunctions(ctx context.Context, token auth.Token, functionIds []string) (functions []model.MeasuringFunction, err error, code int) {
+	temp, _, err, _ := this.deviceRepo.ListFunctions(ctx, client.FunctionListOptions{
 		Ids:    functionIds,
 		Limit:  int64(len(functionIds)),
 		Offset: 0,
@@ -57,7 +51,7 @@ func (this *Lib) GetMeasuringFunctions(token auth.Token, functionIds []string) (
 		return nil, err, http.StatusInternalServerError
 	}
 	for _, function := range temp {
-		functions = append(functions, Function{
+		functions = append(functions, model.MeasuringFunction{
 			Id:          function.Id,
 			Name:        function.Name,
 			Description: function.Description,
@@ -17,101 +17,150 @@
 package pkg
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
 	"github.com/SENERGY-Platform/api-aggregator/pkg/auth"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
 	"github.com/SENERGY-Platform/device-repository/lib/client"
 	"github.com/SENERGY-Platform/models/go/models"
-	"log"
+	"net/http"
+	"slices"
 )
 
-func (this *Lib) CompleteGatewayHistory(token auth.Token, duration string, gateways []map[string]interface{}) (result []map[string]interface{}, err error) {
+func (this *Lib) CompleteGatewayHistory(ctx context.Context, token auth.Token, duration string, gateways []model.AggregatedHub) (result []model.AggregatedHub, err error) {
 	ids := []string{}
-	gatewayMap := map[string]map[string]interface{}{}
 	for _, gateway := range gateways {
-		id, ok := gateway["id"]
-		if !ok {
-			err = errors.New("unable to get gateway id")
-			return
+		ids = append(ids, gateway.Id)
+	}
+	//on partial responses unavailable log_history and log_edge values are omitted
+	historyAvailable, edgesAvailable := true, true
+	var logHistory map[string]model.HistorySeries
+	var logEdges map[string]interface{}
+	group, groupCtx := this.newGroup(ctx)
+	group.Go(func() (err error) {
+		logHistory, err = this.GetGatewayLogHistory(groupCtx, token, ids, duration)
+		if err != nil {
+			if !Degrade(groupCtx, this.config, UpstreamConnectionLog, StepLogHistory, err) {
+				return err
+			}
+			historyAvailable = false
 		}
-		idStr, ok := id.(string)
-		if !ok {
-			err = errors.New("unable to cast gateway id to string")
-			return
+		return nil
+	})
+	group.Go(func() (err error) {
+		logEdges, err = this.GetLogedges(groupCtx, token, "gateway", ids, duration)
+		if err != nil {
+			if !Degrade(groupCtx, this.config, UpstreamConnectionLog, StepLogEdge, err) {
+				return err
+			}
+			edgesAvailable = false
 		}
-		ids = append(ids, idStr)
-		gatewayMap[idStr] = gateway
-	}
-	logHistory, err := this.GetGatewayLogHistory(token, ids, duration)
-	if err != nil {
-		log.Println("ERROR legacyHubTransformations.GetGatewayLogHistory()", err)
-		return result, err
-	}
-	logEdges, err := this.GetLogedges(token, "gateway", ids, duration)
+		return nil
+	})
+	err = group.Wait()
 	if err != nil {
-		log.Println("ERROR legacyDeviceTransformations.GetLogedges()", err)
 		return result, err
 	}
-	for _, id := range ids {
-		gateway := gatewayMap[id]
-		gateway["log_history"] = logHistory[id]
-		gateway["log_edge"] = logEdges[id]
+	result = make([]model.AggregatedHub, 0, len(gateways))
+	for _, gateway := range gateways {
+		if historyAvailable {
+			history := logHistory[gateway.Id]
+			gateway.LogHistory = &history
+		}
+		if edgesAvailable {
+			gateway.LogEdge = logEdges[gateway.Id]
+		}
 		result = append(result, gateway)
 	}
-	return
+	return result, nil
 }
 
-func (this *Lib) ListGateways(token auth.Token, limit int64, offset int64) (result []map[string]interface{}, err error) {
-	hubs, _, err, _ := this.deviceRepo.ListExtendedHubs(token.Jwt(), client.HubListOptions{
-		Limit:      limit,
-		Offset:     offset,
-		SortBy:     "name.asc",
-		Permission: client.READ,
-	})
+// ListGateways returns a page of the hubs of the user matching query and the total count of matching hubs
+func (this *Lib) ListGateways(ctx context.Context, token auth.Token, query HubQuery, limit int64, offset int64) (result []model.AggregatedHub, total int64, err error) {
+	list := this.hubLister(ctx, token, query)
+	if !query.filteredLocally() {
+		hubs, total, err := list(limit, offset)
+		if err != nil {
+			return nil, total, err
+		}
+		return aggregateHubs(hubs), total, nil
+	}
+	//the page and the total count depend on filters of the aggregator, so all hubs of the device-repository query are needed
+	hubs, err := listAll(list)
+	if err != nil {
+		return nil, total, err
+	}
+	hubs = slices.DeleteFunc(hubs, func(hub models.ExtendedHub) bool { return !query.matches(hub) })
+	hubs, total = page(hubs, limit, offset)
+	return aggregateHubs(hubs), total, nil
+}
+
+func (this *Lib) ListAllGateways(ctx context.Context, token auth.Token) (result []model.AggregatedHub, err error) {
+	hubs, err := listAll(this.hubLister(ctx, token, HubQuery{}))
 	if err != nil {
 		return nil, err
 	}
-	return this.legacyHubTransformations(token, hubs)
+	return aggregateHubs(hubs), nil
 }
 
-func (this *Lib) ListAllGateways(token auth.Token) (result []map[string]interface{}, err error) {
-	var limit int64 = 0
-	var offset int64 = 0
-	for {
-		temp, err := this.ListGateways(token, limit, offset)
-		if err != nil {
-			return nil, err
-		}
-		result = append(result, temp...)
-		if int64(len(temp)) < limit {
-			return result, nil
+// GetHub returns the hub with the given id and its devices. if logDuration is set, log_history and log_edge
+// are added to the hub and its devices. devices of the hub the user can not read are omitted.
+// the returned code is the status of the device-repository if the hub can not be read (e.g. 404 or 403).
+func (this *Lib) GetHub(ctx context.Context, token auth.Token, id string, logDuration string) (hub model.AggregatedHub, err error, code int) {
+	extendedHub, err, code := this.deviceRepo.ReadExtendedHub(ctx, token.Jwt(), id, client.READ)
+	if err != nil {
+		return hub, err, code
+	}
+	hubs := aggregateHubs([]models.ExtendedHub{extendedHub})
+	var devices []model.AggregatedDevice
+	group, groupCtx := this.newGroup(ctx)
+	if logDuration != "" {
+		group.Go(func() (err error) {
+			hubs, err = this.CompleteGatewayHistory(groupCtx, token, logDuration, hubs)
+			return err
+		})
+	}
+	group.Go(func() (err error) {
+		devices, err = this.getHubDevices(groupCtx, token, extendedHub.DeviceIds, logDuration)
+		if err != nil && !Degrade(groupCtx, this.config, UpstreamIot, StepHubDevices, err) {
+			return err
 		}
+		return nil
+	})
+	err = group.Wait()
+	if err != nil {
+		return hub, err, http.StatusInternalServerError
 	}
+	hub = hubs[0]
+	hub.Devices = devices
+	return hub, nil, http.StatusOK
 }
 
-func (this *Lib) legacyHubTransformations(token auth.Token, hubs []models.ExtendedHub) (result []map[string]interface{}, err error) {
+func (this *Lib) getHubDevices(ctx context.Context, token auth.Token, deviceIds []string, logDuration string) (devices []model.AggregatedDevice, err error) {
+	devices, _, err = this.LookupDevices(ctx, token, deviceIds, false)
+	if err != nil || logDuration == "" {
+		return devices, err
+	}
+	return this.CompleteDeviceHistory(ctx, token, logDuration, devices)
+}
+
+// hubLister returns a function listing the hubs of the device-repository query of a HubQuery
+func (this *Lib) hubLister(ctx context.Context, token auth.Token, query HubQuery) func(limit int64, offset int64) ([]models.ExtendedHub, int64, error) {
+	return func(limit int64, offset int64) ([]models.ExtendedHub, int64, error) {
+		options := query.listOptions()
+		options.Limit, options.Offset = limit, offset
+		result, total, err, _ := this.deviceRepo.ListExtendedHubs(ctx, token.Jwt(), options)
+		return result, total, err
+	}
+}
+
+func aggregateHubs(hubs []models.ExtendedHub) (result []model.AggregatedHub) {
+	result = []model.AggregatedHub{}
 	for _, hub := range hubs {
-		element := map[string]interface{}{}
-		temp, err := json.Marshal(hub)
-		if err != nil {
-			return nil, err
-		}
-		err = json.Unmarshal(temp, &element)
-		if err != nil {
-			return nil, err
-		}
-		switch hub.ConnectionState {
-		case *client.ConnectionStateOnline:
-			element["log_state"] = "connected"
-		case *client.ConnectionStateOffline:
-			element["log_state"] = "disconnected"
-		case *client.ConnectionStateUnknown:
-			element["log_state"] = "unknown"
-		default:
-			element["log_state"] = "unknown"
-		}
 		//TODO: perm-search transformations for creator, permissions etc
-		result = append(result, element)
+		result = append(result, model.AggregatedHub{
+			ExtendedHub: hub,
+			LogState:    logState(hub.ConnectionState),
+		})
 	}
-	return
+	return result
 }
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/models/go/models"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// HubQuery filters and sorts the hubs of ListGateways.
+// search, sort and log states are applied by the device-repository, the other filters by the aggregator.
+type HubQuery struct {
+	Search     string //name contains (case-insensitive)
+	SortBy     string //<field>.<asc|desc> with a field of HubSortFields; defaults to name.asc
+	LogState   string //model.LogStateConnected, model.LogStateDisconnected or model.LogStateUnknown
+	DeviceId   string //hubs containing the device
+	MinDevices int64  //minimal count of devices
+	MaxDevices *int64 //maximal count of devices; ignored if nil
+}
+
+// HubSortFields maps the sort fields of HubQuery.SortBy to the fields of the device-repository
+var HubSortFields = map[string]string{
+	"name":      "name",
+	"log_state": "connectionstate",
+}
+
+// Validate returns an error if SortBy, LogState or the device counts are invalid
+func (this HubQuery) Validate() error {
+	if this.SortBy != "" {
+		field, direction, _ := strings.Cut(this.SortBy, ".")
+		if _, ok := HubSortFields[field]; !ok || (direction != "asc" && direction != "desc") {
+			return errors.New("unknown sort, expected <field>.<asc|desc> with field in " + strings.Join(slices.Sorted(maps.Keys(HubSortFields)), ", "))
+		}
+	}
+	if _, ok := logStateToConnectionState[this.LogState]; this.LogState != "" && !ok {
+		return errors.New("unknown log_state, expected connected, disconnected or unknown")
+	}
+	if this.MaxDevices != nil && *this.MaxDevices < this.MinDevices {
+		return errors.New("max_devices is smaller than min_devices")
+	}
+	return nil
+}
+
+func (this HubQuery) listOptions() client.HubListOptions {
+	options := client.HubListOptions{
+		Search:     this.Search,
+		SortBy:     "name.asc",
+		Permission: client.READ,
+	}
+	if this.SortBy != "" {
+		field, direction, _ := strings.Cut(this.SortBy, ".")
+		options.SortBy = HubSortFields[field] + "." + direction
+	}
+	if state, ok := logStateToConnectionState[this.LogState]; ok && this.LogState != model.LogStateUnknown {
+		options.ConnectionState = &state
+	}
+	return options
+}
+
+// filteredLocally returns true if the query contains filters the device-repository can not apply
+func (this HubQuery) filteredLocally() bool {
+	return this.DeviceId != "" || this.MinDevices > 0 || this.MaxDevices != nil || this.LogState == model.LogStateUnknown
+}
+
+// matches applies the filters of filteredLocally to a hub of the device-repository
+func (this HubQuery) matches(hub models.ExtendedHub) bool {
+	if this.DeviceId != "" && !slices.Contains(hub.DeviceIds, this.DeviceId) {
+		return false
+	}
+	count := int64(len(hub.DeviceIds))
+	if count < this.MinDevices || (this.MaxDevices != nil && count > *this.MaxDevices) {
+		return false
+	}
+	if this.LogState == model.LogStateUnknown && logState(hub.ConnectionState) != model.LogStateUnknown {
+		return false
+	}
+	return true
+}
@@ -17,6 +17,7 @@
 package pkg
 
 import (
+	"context"
 	"github.com/SENERGY-Platform/api-aggregator/pkg/auth"
 	importRepo "github.com/SENERGY-Platform/import-repository/lib/client"
 	"github.com/SENERGY-Platform/import-repository/lib/model"
@@ -32,8 +33,8 @@ type ImportTypeCriteria struct {
 	AspectId   string
 }
 
-func (this *Lib) GetImportTypesWithAspect(token auth.Token, aspectIds []string) (importTypes []ImportTypeWithCriteria, err error, code int) {
-	temp, _, err, code := this.importRepo.ListImportTypes(token, importRepo.ImportTypeListOptions{
+func (this *Lib) getImportTypesWithAspect(ctx context.Context, token auth.Token, aspectIds []string) (importTypes []ImportTypeWithCriteria, err error, code int) {
+	temp, _, err, code := this.importRepo.ListImportTypes(ctx, token, importRepo.ImportTypeListOptions{
 		Limit:    9999,
 		Offset:   0,
 		SortBy:   "name.asc",
@@ -62,8 +63,8 @@ func importTypeContentVariableToCertList(cv model.ContentVariable) []ImportTypeC
 	return result
 }
 
-func (this *Lib) GetImportTypes(token auth.Token) (importTypes []ImportTypeWithCriteria, err error, code int) {
-	temp, _, err, code := this.importRepo.ListImportTypes(token, importRepo.ImportTypeListOptions{
+func (this *Lib) getImportTypes(ctx context.Context, token auth.Token) (importTypes []ImportTypeWithCriteria, err error, code int) {
+	temp, _, err, code := this.importRepo.ListImportTypes(ctx, token, importRepo.ImportTypeListOptions{
 		Limit:  9999,
 		Offset: 0,
 		SortBy: "name.asc",
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/events"
+	"log/slog"
+)
+
+// changeCommand is the common part of the change messages of the device-repository and the import-repository
+type changeCommand struct {
+	Command string `json:"command"`
+	Id      string `json:"id"`
+}
+
+// StartCacheInvalidation clears the caches affected by the messages of AspectTopic, FunctionTopic, DeviceTypeTopic and ImportTypeTopic.
+// a broker of nil means that cache entries only expire by their ttl.
+func (this *Lib) StartCacheInvalidation(ctx context.Context, broker events.Broker) error {
+	if broker == nil {
+		slog.Info("no cache invalidation broker configured --> cache entries expire by ttl only")
+		return nil
+	}
+	invalidations := []struct {
+		topic      string
+		invalidate func()
+	}{
+		{topic: this.config.AspectTopic, invalidate: func() {
+			//aspect hierarchies change the nodes of all ancestors and the measuring functions of aspects with descendants
+			this.caches.aspectNodes.Clear()
+			this.caches.measuringFunctions.Clear()
+		}},
+		{topic: this.config.FunctionTopic, invalidate: func() {
+			this.caches.measuringFunctions.Clear()
+			this.caches.aspectNodes.Invalidate(aspectNodesWithMeasuringFunctionKey)
+		}},
+		{topic: this.config.DeviceTypeTopic, invalidate: func() {
+			//the device-repository derives the aspects and measuring functions in use from the services of all device types
+			this.caches.aspectNodes.Invalidate(aspectNodesWithMeasuringFunctionKey)
+			this.caches.measuringFunctions.Clear()
+		}},
+		{topic: this.config.ImportTypeTopic, invalidate: func() {
+			//import types may be shared, so all users are affected
+			this.caches.importTypes.Clear()
+		}},
+	}
+	for _, invalidation := range invalidations {
+		if invalidation.topic == "" || invalidation.topic == "-" {
+			continue
+		}
+		topic, invalidate := invalidation.topic, invalidation.invalidate
+		err := broker.Subscribe(ctx, topic, func(message []byte) {
+			command := changeCommand{}
+			err := json.Unmarshal(message, &command)
+			if err != nil {
+				slog.Warn("unable to parse change message --> invalidate anyway", "topic", topic, "error", err)
+			}
+			slog.Debug("invalidate caches", "topic", topic, "command", command.Command, "id", command.Id)
+			invalidate()
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
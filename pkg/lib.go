@@ -17,40 +17,65 @@
 package pkg
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
+	"context"
 	"github.com/SENERGY-Platform/api-aggregator/pkg/auth"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/cache"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/metrics"
 	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
-	"github.com/SENERGY-Platform/device-repository/lib/client"
-	importRepo "github.com/SENERGY-Platform/import-repository/lib/client"
-	"io"
-	"net/http"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/upstream"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
 	"net/url"
-	"runtime/debug"
 )
 
 type Interface interface {
 	Config() Config
-	ListGateways(token auth.Token, limit int64, offset int64) (result []map[string]interface{}, err error)
-	GetExtendedProcessList(token auth.Token, query url.Values) (result []map[string]interface{}, err error)
-	CompleteDeviceHistory(token auth.Token, duration string, devices []map[string]interface{}) (result []map[string]interface{}, err error)
-	CompleteGatewayHistory(token auth.Token, duration string, devices []map[string]interface{}) (result []map[string]interface{}, err error)
-	ListAllGateways(token auth.Token) (result []map[string]interface{}, err error)
-	FindDevices(token auth.Token, limit int, offset int) ([]map[string]interface{}, error)
-	GetMeasuringFunctionsForAspect(token auth.Token, aspectId string) (functions []Function, err error, code int)
-	GetMeasuringFunctions(token auth.Token, functionIds []string) (functions []Function, err error, code int)
-	GetImportTypesWithAspect(token auth.Token, aspectIds []string) (importTypes []ImportTypeWithCriteria, err error, code int)
-	GetAspectNodes(ids []string, token auth.Token) ([]model.AspectNode, error)
-	GetAspectNodesWithMeasuringFunction(token auth.Token) ([]model.AspectNode, error)
-	GetImportTypes(token auth.Token) (importTypes []ImportTypeWithCriteria, err error, code int)
-	GetDeviceClassUses(token auth.Token) (result interface{}, err error)
+	GetHub(ctx context.Context, token auth.Token, id string, logDuration string) (hub model.AggregatedHub, err error, code int)
+	ListGateways(ctx context.Context, token auth.Token, query HubQuery, limit int64, offset int64) (result []model.AggregatedHub, total int64, err error)
+	GetExtendedProcessList(ctx context.Context, token auth.Token, query url.Values) (result []model.AggregatedProcess, err error)
+	GetDependents(ctx context.Context, token auth.Token, deviceIds []string, eventIds []string) (result model.DependentsLookupResult, err error)
+	GetDevice(ctx context.Context, token auth.Token, id string, expansions DeviceExpansions) (device model.AggregatedDevice, err error, code int)
+	LookupDevices(ctx context.Context, token auth.Token, ids []string, fullDt bool) (devices []model.AggregatedDevice, deviceErrors []model.DeviceError, err error)
+	ExpandDevices(ctx context.Context, token auth.Token, expansions DeviceExpansions, devices []model.AggregatedDevice) (result []model.AggregatedDevice, err error)
+	CompleteDeviceHistory(ctx context.Context, token auth.Token, duration string, devices []model.AggregatedDevice) (result []model.AggregatedDevice, err error)
+	CompleteGatewayHistory(ctx context.Context, token auth.Token, duration string, gateways []model.AggregatedHub) (result []model.AggregatedHub, err error)
+	ListAllGateways(ctx context.Context, token auth.Token) (result []model.AggregatedHub, err error)
+	FindDevices(ctx context.Context, token auth.Token, query DeviceQuery, limit int64, offset int64) (devices []model.AggregatedDevice, total int64, err error)
+	GetMeasuringFunctionsForAspect(ctx context.Context, token auth.Token, aspectId string) (functions []model.MeasuringFunction, err error, code int)
+	GetMeasuringFunctions(ctx context.Context, token auth.Token, functionIds []string) (functions []model.MeasuringFunction, err error, code int)
+	GetImportTypesWithAspect(ctx context.Context, token auth.Token, aspectIds []string) (importTypes []ImportTypeWithCriteria, err error, code int)
+	GetAspectNodes(ctx context.Context, ids []string, token auth.Token) ([]model.AspectNode, error)
+	GetAspectNodesWithMeasuringFunction(ctx context.Context, token auth.Token) ([]model.AspectNode, error)
+	GetImportTypes(ctx context.Context, token auth.Token) (importTypes []ImportTypeWithCriteria, err error, code int)
+	GetDeviceClassUses(ctx context.Context, token auth.Token) (result model.DeviceClassUses, err error)
+	GetCircuitBreakerStatus() []upstream.CircuitBreakerStatus
+	GetCacheStats() []cache.Stats
+	Metrics() *metrics.Metrics
 }
 
+const (
+	UpstreamIot               = "iot"
+	UpstreamImportRepo        = "import-repository"
+	UpstreamConnectionLog     = "connection-log"
+	UpstreamCamundaWrapper    = "camunda-wrapper"
+	UpstreamProcessDeployment = "process-deployment"
+	UpstreamEventManager      = "event-manager"
+)
+
 type Lib struct {
 	config     Config
-	deviceRepo client.Interface
-	importRepo importRepo.Interface
+	breakers   *upstream.CircuitBreakers
+	metrics    *metrics.Metrics
+	caches     caches
+	coalescing singleflight.Group
+	deviceRepo upstream.DeviceRepository
+	importRepo upstream.ImportRepository
+
+	iot               *upstream.Client
+	connectionLog     *upstream.Client
+	camundaWrapper    *upstream.Client
+	processDeployment *upstream.Client
+	eventManager      *upstream.Client
 }
 
 func (this *Lib) Config() Config {
@@ -58,61 +83,54 @@ func (this *Lib) Config() Config {
 }
 
 func New(config Config) *Lib {
-	return &Lib{config: config, deviceRepo: client.NewClient(config.IotUrl, nil), importRepo: importRepo.NewClient(config.ImportRepoUrl)}
+	return NewWithMiddleware(config, nil)
 }
 
-func post(token string, url string, contentType string, body io.Reader) (resp *http.Response, err error) {
-	req, err := http.NewRequest("POST", url, body)
-	if err != nil {
-		debug.PrintStack()
-		return nil, err
+// NewWithMiddleware is like New but wraps the transport of every upstream with middleware (may be nil); see Config.UpstreamMiddleware
+func NewWithMiddleware(config Config, middleware upstream.Middleware) *Lib {
+	breakers := upstream.NewCircuitBreakers(int(config.CircuitBreakerFailureThreshold), parseDurationOrZero("circuit_breaker_open_timeout", config.CircuitBreakerOpenTimeout))
+	retry := config.UpstreamRetryPolicy()
+	m := metrics.New()
+	options := func(timeout string) upstream.Options {
+		return upstream.Options{
+			Timeout:    config.UpstreamTimeout(timeout),
+			Retry:      retry,
+			Breakers:   breakers,
+			Observer:   m,
+			Middleware: middleware,
+		}
+	}
+	iot := upstream.New(UpstreamIot, config.IotUrl, options(config.IotTimeout))
+	importRepo := upstream.New(UpstreamImportRepo, config.ImportRepoUrl, options(config.ImportRepoTimeout))
+	return &Lib{
+		config:            config,
+		breakers:          breakers,
+		metrics:           m,
+		caches:            newCaches(config),
+		deviceRepo:        upstream.NewDeviceRepository(iot),
+		importRepo:        upstream.NewImportRepository(importRepo),
+		iot:               iot,
+		connectionLog:     upstream.New(UpstreamConnectionLog, config.ConnectionLogUrl, options(config.ConnectionLogTimeout)),
+		camundaWrapper:    upstream.New(UpstreamCamundaWrapper, config.CamundaWrapperUrl, options(config.CamundaWrapperTimeout)),
+		processDeployment: upstream.New(UpstreamProcessDeployment, config.ProcessDeploymentUrl, options(config.ProcessDeploymentTimeout)),
+		eventManager:      upstream.New(UpstreamEventManager, config.EventManagerUrl, options(config.EventManagerTimeout)),
 	}
-	req.Header.Set("Content-Type", contentType)
-	req.Header.Set("Authorization", token)
-	return http.DefaultClient.Do(req)
 }
 
-func postJson(token string, url string, in interface{}, out interface{}) (err error) {
-	requestBody := new(bytes.Buffer)
-	err = json.NewEncoder(requestBody).Encode(in)
-	if err != nil {
-		return err
-	}
-	resp, err := post(token, url, "application/json", requestBody)
-	if err != nil {
-		debug.PrintStack()
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		responseMsg, _ := io.ReadAll(resp.Body)
-		debug.PrintStack()
-		return errors.New(string(responseMsg))
-	}
-	return json.NewDecoder(resp.Body).Decode(out)
+func (this *Lib) GetCircuitBreakerStatus() []upstream.CircuitBreakerStatus {
+	return this.breakers.Status()
 }
 
-func get(token string, url string) (resp *http.Response, err error) {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		debug.PrintStack()
-		return nil, err
-	}
-	req.Header.Set("Authorization", token)
-	return http.DefaultClient.Do(req)
+func (this *Lib) Metrics() *metrics.Metrics {
+	return this.metrics
 }
 
-func GetJson(token string, url string, out interface{}) (err error) {
-	resp, err := get(token, url)
-	if err != nil {
-		debug.PrintStack()
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		responseMsg, _ := io.ReadAll(resp.Body)
-		debug.PrintStack()
-		return errors.New(string(responseMsg))
+// newGroup returns an errgroup for independent upstream calls of one aggregation step.
+// the first failing call cancels the others; at most UpstreamConcurrency calls run at the same time.
+func (this *Lib) newGroup(ctx context.Context) (*errgroup.Group, context.Context) {
+	group, ctx := errgroup.WithContext(ctx)
+	if this.config.UpstreamConcurrency > 0 {
+		group.SetLimit(int(this.config.UpstreamConcurrency))
 	}
-	return json.NewDecoder(resp.Body).Decode(out)
+	return group, ctx
 }
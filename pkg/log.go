@@ -17,11 +17,14 @@
 package pkg
 
 import (
+	"context"
 	"github.com/SENERGY-Platform/api-aggregator/pkg/auth"
-	"log"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"log/slog"
+	"slices"
 )
 
-func (this *Lib) SetOnlineState(token auth.Token, dependencies []Dependencies) (result []Dependencies, err error) {
+func (this *Lib) SetOnlineState(ctx context.Context, token auth.Token, dependencies []Dependencies) (result []Dependencies, err error) {
 
 	//create device id list
 	//use map to prevent duplicate ids
@@ -35,6 +38,7 @@ func (this *Lib) SetOnlineState(token auth.Token, dependencies []Dependencies) (
 	for id, _ := range deviceidset {
 		deviceids = append(deviceids, id)
 	}
+	slices.Sort(deviceids) //stable request bodies for recorded upstream exchanges
 
 	//create event id list
 	//use map to prevent duplicate ids
@@ -48,17 +52,36 @@ func (this *Lib) SetOnlineState(token auth.Token, dependencies []Dependencies) (
 	for id, _ := range eventidset {
 		eventids = append(eventids, id)
 	}
+	slices.Sort(eventids)
 
-	//get device states
+	//get device and event states concurrently
+	//on partial responses unavailable states are marked as unknown
 	devicestates := map[string]bool{}
-	devicestates, err = this.GetDeviceLogStates(token, deviceids)
-	if err != nil {
-		return result, err
-	}
-
-	//get event states
+	devicestatesUnknown := false
 	eventstates := map[string]bool{}
-	eventstates, err = this.CheckEventStates(token.Token, eventids)
+	eventstatesUnknown := false
+	group, groupCtx := this.newGroup(ctx)
+	group.Go(func() (err error) {
+		devicestates, err = this.GetDeviceLogStates(groupCtx, token, deviceids)
+		if err != nil {
+			if !Degrade(groupCtx, this.config, UpstreamConnectionLog, StepOnlineState, err) {
+				return err
+			}
+			devicestatesUnknown = true
+		}
+		return nil
+	})
+	group.Go(func() (err error) {
+		eventstates, err = this.CheckEventStates(groupCtx, token.Token, eventids)
+		if err != nil {
+			if !Degrade(groupCtx, this.config, UpstreamEventManager, StepOnlineState, err) {
+				return err
+			}
+			eventstatesUnknown = true
+		}
+		return nil
+	})
+	err = group.Wait()
 	if err != nil {
 		return result, err
 	}
@@ -68,20 +91,24 @@ func (this *Lib) SetOnlineState(token auth.Token, dependencies []Dependencies) (
 		dependency.Online = true
 		for index, device := range dependency.Devices {
 			device.Online = true
+			device.Unknown = devicestatesUnknown
 			temp, ok := devicestates[device.DeviceId]
 			if ok && !temp {
 				device.Online = false
 				dependency.Online = false
 			}
+			dependency.Unknown = dependency.Unknown || device.Unknown
 			dependency.Devices[index] = device
 		}
 		for index, event := range dependency.Events {
 			event.Online = true
+			event.Unknown = eventstatesUnknown
 			temp, ok := eventstates[event.EventId]
 			if ok && !temp {
 				event.Online = false
 				dependency.Online = false
 			}
+			dependency.Unknown = dependency.Unknown || event.Unknown
 			dependency.Events[index] = event
 		}
 		result = append(result, dependency)
@@ -89,86 +116,92 @@ func (this *Lib) SetOnlineState(token auth.Token, dependencies []Dependencies) (
 	return result, nil
 }
 
-func (this *Lib) GetDeviceLogStates(token auth.Token, deviceIds []string) (result map[string]bool, err error) {
+func (this *Lib) GetDeviceLogStates(ctx context.Context, token auth.Token, deviceIds []string) (result map[string]bool, err error) {
 	result = map[string]bool{}
-	if this.Config().ConnectionLogUrl == "" || this.Config().ConnectionLogUrl == "-" {
-		log.Println("WARNING: no connectionlog url configured")
+	if !this.connectionLog.Enabled() {
+		slog.WarnContext(ctx, "no connection_log_url configured")
 		return
 	}
-	err = postJson(token.Token, this.config.ConnectionLogUrl+"/intern/state/device/check", deviceIds, &result)
-	return
+	return batchMap(ctx, this, deviceIds, func(ctx context.Context, ids []string) (result map[string]bool, err error) {
+		err = this.connectionLog.QueryJson(ctx, token.Token, "/intern/state/device/check", ids, &result)
+		return
+	})
 }
 
-func (this *Lib) GetGatewayLogStates(token auth.Token, ids []string) (result map[string]bool, err error) {
+func (this *Lib) GetGatewayLogStates(ctx context.Context, token auth.Token, ids []string) (result map[string]bool, err error) {
 	result = map[string]bool{}
-	if this.Config().ConnectionLogUrl == "" || this.Config().ConnectionLogUrl == "-" {
-		log.Println("WARNING: no connectionlog url configured")
+	if !this.connectionLog.Enabled() {
+		slog.WarnContext(ctx, "no connection_log_url configured")
 		for _, id := range ids {
 			result[id] = true
 		}
 		return
 	}
-	err = postJson(token.Token, this.config.ConnectionLogUrl+"/intern/state/gateway/check", ids, &result)
-	return
+	return batchMap(ctx, this, ids, func(ctx context.Context, ids []string) (result map[string]bool, err error) {
+		err = this.connectionLog.QueryJson(ctx, token.Token, "/intern/state/gateway/check", ids, &result)
+		return
+	})
 }
 
-func (this *Lib) GetDeviceLogHistory(token auth.Token, deviceIds []string, duration string) (result map[string]HistorySeries, err error) {
-	if this.Config().ConnectionLogUrl == "" || this.Config().ConnectionLogUrl == "-" {
-		log.Println("WARNING: no connectionlog url configured")
-		result = map[string]HistorySeries{}
+func (this *Lib) GetDeviceLogHistory(ctx context.Context, token auth.Token, deviceIds []string, duration string) (result map[string]model.HistorySeries, err error) {
+	if !this.connectionLog.Enabled() {
+		slog.WarnContext(ctx, "no connection_log_url configured")
+		result = map[string]model.HistorySeries{}
 		return
 	}
-	return this.GetLogHistory(token, "device", deviceIds, duration)
+	return this.GetLogHistory(ctx, token, "device", deviceIds, duration)
 }
 
-func (this *Lib) GetGatewayLogHistory(token auth.Token, ids []string, duration string) (result map[string]HistorySeries, err error) {
-	return this.GetLogHistory(token, "gateway", ids, duration)
+func (this *Lib) GetGatewayLogHistory(ctx context.Context, token auth.Token, ids []string, duration string) (result map[string]model.HistorySeries, err error) {
+	return this.GetLogHistory(ctx, token, "gateway", ids, duration)
 }
 
 type HistoryResult struct {
-	Series []HistorySeries `json:"Series"`
+	Series []model.HistorySeries `json:"Series"`
 }
 
-type HistorySeries struct {
-	Name    string            `json:"name"`
-	Tags    map[string]string `json:"tags"`
-	Columns []string          `json:"columns"`
-	Values  [][]interface{}   `json:"values"`
-}
-
-func (this *Lib) GetLogHistory(token auth.Token, kind string, ids []string, duration string) (result map[string]HistorySeries, err error) {
-	result = map[string]HistorySeries{}
-	if this.Config().ConnectionLogUrl == "" || this.Config().ConnectionLogUrl == "-" {
-		log.Println("WARNING: no connectionlog url configured")
+func (this *Lib) GetLogHistory(ctx context.Context, token auth.Token, kind string, ids []string, duration string) (result map[string]model.HistorySeries, err error) {
+	result = map[string]model.HistorySeries{}
+	if !this.connectionLog.Enabled() {
+		slog.WarnContext(ctx, "no connection_log_url configured")
 		return
 	}
-	temp := []HistoryResult{}
-	err = postJson(token.Token, this.config.ConnectionLogUrl+"/intern/history/"+kind+"/"+duration, ids, &temp)
-	if err != nil {
+	return batchMap(ctx, this, ids, func(ctx context.Context, ids []string) (result map[string]model.HistorySeries, err error) {
+		temp := []HistoryResult{}
+		err = this.connectionLog.QueryJson(ctx, token.Token, "/intern/history/"+kind+"/"+duration, ids, &temp)
+		if err != nil {
+			return result, err
+		}
+		result = map[string]model.HistorySeries{}
+		for _, element := range temp {
+			for _, series := range element.Series {
+				result[series.Tags[kind]] = series
+			}
+		}
 		return result, err
-	}
-	for _, series := range temp[0].Series {
-		result[series.Tags[kind]] = series
-	}
-	return result, err
+	})
 }
 
-func (this *Lib) GetLogstarts(token auth.Token, kind string, ids []string) (result map[string]interface{}, err error) {
+func (this *Lib) GetLogstarts(ctx context.Context, token auth.Token, kind string, ids []string) (result map[string]interface{}, err error) {
 	result = map[string]interface{}{}
-	if this.Config().ConnectionLogUrl == "" || this.Config().ConnectionLogUrl == "-" {
-		log.Println("WARNING: no connectionlog url configured")
+	if !this.connectionLog.Enabled() {
+		slog.WarnContext(ctx, "no connection_log_url configured")
 		return
 	}
-	err = postJson(token.Token, this.config.ConnectionLogUrl+"/intern/logstarts/"+kind, ids, &result)
-	return
+	return batchMap(ctx, this, ids, func(ctx context.Context, ids []string) (result map[string]interface{}, err error) {
+		err = this.connectionLog.QueryJson(ctx, token.Token, "/intern/logstarts/"+kind, ids, &result)
+		return
+	})
 }
 
-func (this *Lib) GetLogedges(token auth.Token, kind string, ids []string, duration string) (result map[string]interface{}, err error) {
+func (this *Lib) GetLogedges(ctx context.Context, token auth.Token, kind string, ids []string, duration string) (result map[string]interface{}, err error) {
 	result = map[string]interface{}{}
-	if this.Config().ConnectionLogUrl == "" || this.Config().ConnectionLogUrl == "-" {
-		log.Println("WARNING: no connectionlog url configured")
+	if !this.connectionLog.Enabled() {
+		slog.WarnContext(ctx, "no connection_log_url configured")
 		return
 	}
-	err = postJson(token.Token, this.config.ConnectionLogUrl+"/intern/logedge/"+kind+"/"+duration, ids, &result)
-	return
+	return batchMap(ctx, this, ids, func(ctx context.Context, ids []string) (result map[string]interface{}, err error) {
+		err = this.connectionLog.QueryJson(ctx, token.Token, "/intern/logedge/"+kind+"/"+duration, ids, &result)
+		return
+	})
 }
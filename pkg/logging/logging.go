@@ -0,0 +1,115 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logging
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+const (
+	FormatText = "text"
+	FormatJson = "json"
+)
+
+// RequestIdHeader is read from incoming requests, set on responses and forwarded to upstream services
+const RequestIdHeader = "X-Request-Id"
+
+// New creates a logger writing to w. level is one of "debug", "info", "warn" or "error" (default "info"),
+// format is "text" or "json" (default "text").
+// records logged with a context contain the attributes added by With (e.g. request_id, user_id) and the trace id.
+func New(w io.Writer, level string, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if level != "" {
+		err := lvl.UnmarshalText([]byte(level))
+		if err != nil {
+			return nil, err
+		}
+	}
+	options := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case FormatText, "":
+		handler = slog.NewTextHandler(w, options)
+	case FormatJson:
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return nil, errors.New("unknown log format " + format)
+	}
+	return slog.New(&contextHandler{Handler: handler}), nil
+}
+
+// Setup replaces the slog default logger (and with it the output of the standard log package)
+func Setup(w io.Writer, level string, format string) error {
+	logger, err := New(w, level, format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+type attrsKey struct{}
+type requestIdKey struct{}
+
+// With returns a context whose log records contain args (key-value pairs or slog.Attr values)
+func With(ctx context.Context, args ...any) context.Context {
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	record := slog.Record{}
+	record.Add(args...)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	return context.WithValue(ctx, attrsKey{}, attrs[:len(attrs):len(attrs)])
+}
+
+// WithRequestId returns a context with the request id attribute, which is also forwarded to upstream services
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return With(context.WithValue(ctx, requestIdKey{}, id), "request_id", id)
+}
+
+func RequestId(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (this *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs, ok := ctx.Value(attrsKey{}).([]slog.Attr); ok {
+		record.AddAttrs(attrs...)
+	}
+	if span := trace.SpanContextFromContext(ctx); span.HasTraceID() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()))
+	}
+	return this.Handler.Handle(ctx, record)
+}
+
+func (this *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: this.Handler.WithAttrs(attrs)}
+}
+
+func (this *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: this.Handler.WithGroup(name)}
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Metrics collects prometheus metrics of the aggregator endpoints and of the upstream services they depend on.
+// every Metrics instance uses its own registry.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+
+	upstreamRequests *prometheus.CounterVec
+	upstreamErrors   *prometheus.CounterVec
+	upstreamDuration *prometheus.HistogramVec
+
+	coalescingCalls *prometheus.CounterVec
+	coalesced       *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	reg := prometheus.NewRegistry()
+	result := &Metrics{
+		registry: reg,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "aggregator_http_requests_total",
+			Help: "count of handled http requests by route, method and status code",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "aggregator_http_request_duration_seconds",
+			Help:    "latency of handled http requests by route and method",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		upstreamRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "aggregator_upstream_requests_total",
+			Help: "count of requests sent to upstream services by upstream, endpoint, method and status (status code, 'error' or 'circuit_open')",
+		}, []string{"upstream", "endpoint", "method", "status"}),
+		upstreamErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "aggregator_upstream_errors_total",
+			Help: "count of failed upstream requests (transport errors, open circuit breakers and 5xx responses)",
+		}, []string{"upstream", "endpoint", "method"}),
+		upstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "aggregator_upstream_request_duration_seconds",
+			Help:    "latency of requests sent to upstream services",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"upstream", "endpoint", "method"}),
+		coalescingCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "aggregator_coalescing_calls_total",
+			Help: "count of calls of coalescable operations (e.g. device-class-uses) by operation",
+		}, []string{"operation"}),
+		coalesced: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "aggregator_coalesced_calls_total",
+			Help: "count of calls that received the result of a concurrent identical call instead of executing it by operation",
+		}, []string{"operation"}),
+	}
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		result.requests,
+		result.requestDuration,
+		result.upstreamRequests,
+		result.upstreamErrors,
+		result.upstreamDuration,
+		result.coalescingCalls,
+		result.coalesced,
+	)
+	return result
+}
+
+// Handler serves the collected metrics in the prometheus exposition format
+func (this *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(this.registry, promhttp.HandlerOpts{Registry: this.registry})
+}
+
+func (this *Metrics) Registry() *prometheus.Registry {
+	return this.registry
+}
+
+func (this *Metrics) ObserveRequest(route string, method string, status int, duration time.Duration) {
+	this.requests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	this.requestDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+// ObserveUpstreamRequest implements upstream.Observer
+func (this *Metrics) ObserveUpstreamRequest(upstream string, method string, endpoint string, status string, failed bool, duration time.Duration) {
+	this.upstreamRequests.WithLabelValues(upstream, endpoint, method, status).Inc()
+	this.upstreamDuration.WithLabelValues(upstream, endpoint, method).Observe(duration.Seconds())
+	if failed {
+		this.upstreamErrors.WithLabelValues(upstream, endpoint, method).Inc()
+	}
+}
+
+// ObserveCoalescing counts a call of a coalescable operation; coalesced is true if the call received the result of another call
+func (this *Metrics) ObserveCoalescing(operation string, coalesced bool) {
+	this.coalescingCalls.WithLabelValues(operation).Inc()
+	if coalesced {
+		this.coalesced.WithLabelValues(operation).Inc()
+	}
+}
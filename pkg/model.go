@@ -22,6 +22,7 @@ type Dependencies struct {
 	Devices      []DeviceDependency `json:"devices" bson:"devices"`
 	Events       []EventDependency  `json:"events" bson:"events"`
 	Online       bool               `json:"-"`
+	Unknown      bool               `json:"-"` //true if the state of at least one device or event could not be checked
 }
 
 type DeviceDependency struct {
@@ -29,22 +30,17 @@ type DeviceDependency struct {
 	Name          string         `json:"name" bson:"name"`
 	BpmnResources []BpmnResource `json:"bpmn_resources" bson:"bpmn_resources"`
 	Online        bool           `json:"-"`
+	Unknown       bool           `json:"-"`
 }
 
 type EventDependency struct {
 	EventId       string         `json:"event_id" bson:"event_id"`
 	BpmnResources []BpmnResource `json:"bpmn_resources" bson:"bpmn_resources"`
 	Online        bool           `json:"-"`
+	Unknown       bool           `json:"-"`
 }
 
 type BpmnResource struct {
 	Id    string `json:"id" bson:"id"`
-	label string `json:"label" bson:"label"`
-}
-
-type OfflineReason struct {
-	Type           string      `json:"type"`
-	Id             string      `json:"id"`
-	AdditionalInfo interface{} `json:"additional_info,omitempty"`
-	Description    string      `json:"description"`
+	Label string `json:"label" bson:"label"`
 }
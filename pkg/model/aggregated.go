@@ -0,0 +1,165 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"github.com/SENERGY-Platform/models/go/models"
+)
+
+// log_state values of devices and hubs
+const (
+	LogStateConnected    = "connected"
+	LogStateDisconnected = "disconnected"
+	LogStateUnknown      = "unknown"
+)
+
+// online_state values of processes
+const (
+	OnlineStateOnline  = "online"
+	OnlineStateOffline = "offline"
+	OnlineStateUnknown = "unknown"
+)
+
+// AggregatedDevice is a device of the device-repository enriched with connection-log information.
+// LogHistory and LogEdge are only set if a log duration was requested and the connection-log could be reached.
+// LogStart, DeviceClass, Hubs and DependentProcesses are only set if their expansion was requested.
+type AggregatedDevice struct {
+	models.ExtendedDevice
+	LogState           string              `json:"log_state"`
+	Creator            string              `json:"creator"`
+	LogHistory         *HistorySeries      `json:"log_history,omitempty"`
+	LogEdge            interface{}         `json:"log_edge,omitempty"`
+	LogStart           interface{}         `json:"log_start,omitempty"`
+	DeviceClass        *models.DeviceClass `json:"device_class,omitempty"`
+	Hubs               []HubReference      `json:"hubs,omitempty"`
+	DependentProcesses []ProcessReference  `json:"dependent_processes,omitempty"`
+}
+
+// HubReference is a hub containing a device
+type HubReference struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ProcessReference is a process deployment depending on a device
+type ProcessReference struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// DeviceLookup is the request of POST /devices/query
+type DeviceLookup struct {
+	Ids    []string `json:"ids"`
+	Log    string   `json:"log,omitempty"`    //optional influxdb duration; adds log_history and log_edge
+	Expand []string `json:"expand,omitempty"` //optional expansions like the expand query parameter of /devices
+}
+
+// DeviceLookupResult contains the devices of a DeviceLookup in the requested order
+// and an error for every id that could not be read (e.g. 404 or 403)
+type DeviceLookupResult struct {
+	Devices []AggregatedDevice `json:"devices"`
+	Errors  []DeviceError      `json:"errors"`
+}
+
+type DeviceError struct {
+	Id         string `json:"id"`
+	StatusCode int    `json:"status_code"`
+	Message    string `json:"message"`
+}
+
+// Dependent is a process deployment referencing a device or event filter in Tasks
+type Dependent struct {
+	Id    string     `json:"id"`
+	Name  string     `json:"name"`
+	Tasks []BpmnTask `json:"tasks"`
+}
+
+type BpmnTask struct {
+	Id    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// DependentsLookup is the request of POST /dependents/query
+type DependentsLookup struct {
+	DeviceIds []string `json:"device_ids,omitempty"`
+	EventIds  []string `json:"event_ids,omitempty"` //ids of event filters
+}
+
+// DependentsLookupResult lists the dependents of every id of a DependentsLookup by id
+type DependentsLookupResult struct {
+	Devices map[string][]Dependent `json:"devices"`
+	Events  map[string][]Dependent `json:"events"`
+}
+
+// AggregatedHub is a hub of the device-repository enriched with connection-log information.
+// LogHistory and LogEdge are only set if a log duration was requested and the connection-log could be reached.
+// Devices is only set by the hub detail endpoint.
+type AggregatedHub struct {
+	models.ExtendedHub
+	LogState   string             `json:"log_state"`
+	LogHistory *HistorySeries     `json:"log_history,omitempty"`
+	LogEdge    interface{}        `json:"log_edge,omitempty"`
+	Devices    []AggregatedDevice `json:"devices,omitempty"`
+}
+
+// AggregatedProcess is a process deployment of the camunda-wrapper enriched with the online state of its dependencies.
+// Online is nil if the state of at least one dependency is unknown.
+type AggregatedProcess struct {
+	ProcessDeployment
+	Online         *bool           `json:"online"`
+	OnlineState    string          `json:"online_state"`
+	OfflineReasons []OfflineReason `json:"offline_reasons"`
+}
+
+// ProcessDeployment is a camunda deployment as returned by the camunda-wrapper
+type ProcessDeployment struct {
+	Id             string        `json:"id"`
+	Name           string        `json:"name"`
+	Source         string        `json:"source"`
+	DeploymentTime string        `json:"deploymentTime"`
+	TenantId       *string       `json:"tenantId"`
+	Links          []interface{} `json:"links"`
+}
+
+// MeasuringFunction is a function returned by /aspects/:id/measuring-functions
+type MeasuringFunction struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ConceptId   string `json:"concept_id"`
+	RdfType     string `json:"rdf_type"`
+}
+
+// DeviceClassUses lists the device-classes used by the devices of a user
+type DeviceClassUses struct {
+	DeviceClasses []models.DeviceClass `json:"device-classes"`
+	UsedDevices   map[string][]string  `json:"used-devices"`
+}
+
+type OfflineReason struct {
+	Type           string      `json:"type"`
+	Id             string      `json:"id"`
+	AdditionalInfo interface{} `json:"additional_info,omitempty"`
+	Description    string      `json:"description"`
+}
+
+type HistorySeries struct {
+	Name    string            `json:"name"`
+	Tags    map[string]string `json:"tags"`
+	Columns []string          `json:"columns"`
+	Values  [][]interface{}   `json:"values"`
+}
@@ -0,0 +1,29 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+// Page is the envelope of paginated /v2 list responses.
+// the cursors are opaque and may be passed as cursor query parameter to get the next or previous page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int64  `json:"total"`  //count of all elements, as reported by the upstream service
+	Limit      int64  `json:"limit"`  //requested page size
+	Offset     int64  `json:"offset"` //offset of the first item; resolved from the cursor if one was used
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"` //empty on the last page
+	PrevCursor string `json:"prev_cursor,omitempty"` //empty on the first page
+}
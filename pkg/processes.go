@@ -17,36 +17,32 @@
 package pkg
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
 	"github.com/SENERGY-Platform/api-aggregator/pkg/auth"
-	"io/ioutil"
-	"log"
-	"net/http"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"log/slog"
 	"net/url"
-	"runtime/debug"
 	"strings"
 )
 
-func (this *Lib) GetExtendedProcessList(token auth.Token, query url.Values) (result []map[string]interface{}, err error) {
-	processes, err := this.GetProcessDeploymentList(token, query)
+func (this *Lib) GetExtendedProcessList(ctx context.Context, token auth.Token, query url.Values) (result []model.AggregatedProcess, err error) {
+	processes, err := this.GetProcessDeploymentList(ctx, token, query)
 	if err != nil {
 		return result, err
 	}
 	ids := []string{}
 	for _, process := range processes {
-		id, ok := process["id"].(string)
-		if !ok {
-			log.Println("ERROR: unable to read process id", process)
-			return result, errors.New("unable to read process id")
-		}
-		ids = append(ids, id)
+		ids = append(ids, process.Id)
 	}
-	metadata, err := this.GetProcessDependencyList(token, ids)
+	dependenciesUnknown := false
+	metadata, err := this.GetProcessDependencyList(ctx, token, ids)
 	if err != nil {
-		return result, err
+		if !Degrade(ctx, this.config, UpstreamProcessDeployment, StepDependencies, err) {
+			return result, err
+		}
+		dependenciesUnknown = true
 	}
-	metadata, err = this.SetOnlineState(token, metadata)
+	metadata, err = this.SetOnlineState(ctx, token, metadata)
 	if err != nil {
 		return result, err
 	}
@@ -54,67 +50,53 @@ func (this *Lib) GetExtendedProcessList(token auth.Token, query url.Values) (res
 	for _, m := range metadata {
 		metadataIndex[m.DeploymentId] = m
 	}
+	result = []model.AggregatedProcess{}
 	for _, process := range processes {
-		id, ok := process["id"].(string)
-		if !ok {
-			log.Println("ERROR: unable to read process id", process)
-			return result, errors.New("unable to read process id")
-		}
-		process["online"] = true
-		process["offline_reasons"] = []OfflineReason{}
-		if !metadataIndex[id].Online {
-			process["online"] = false
-			process["offline_reasons"], err = getOfflineReasons(metadataIndex[id])
+		dependency := metadataIndex[process.Id]
+		element := model.AggregatedProcess{ProcessDeployment: process, OfflineReasons: []model.OfflineReason{}}
+		switch {
+		case !dependency.Online && !dependenciesUnknown:
+			online := false
+			element.Online = &online
+			element.OnlineState = model.OnlineStateOffline
+			element.OfflineReasons = getOfflineReasons(dependency)
+		case dependency.Unknown || dependenciesUnknown:
+			//partial response: "online" is null if the state could not be checked
+			element.OnlineState = model.OnlineStateUnknown
+		default:
+			online := true
+			element.Online = &online
+			element.OnlineState = model.OnlineStateOnline
 		}
-		result = append(result, process)
+		result = append(result, element)
 	}
-	return
+	return result, nil
 }
 
-func (this *Lib) GetProcessDeploymentList(token auth.Token, query url.Values) (result []map[string]interface{}, err error) {
-	if this.Config().CamundaWrapperUrl == "" || this.Config().CamundaWrapperUrl == "-" {
-		log.Println("WARNING: no CamundaWrapperUrl url configured")
+func (this *Lib) GetProcessDeploymentList(ctx context.Context, token auth.Token, query url.Values) (result []model.ProcessDeployment, err error) {
+	if !this.camundaWrapper.Enabled() {
+		slog.WarnContext(ctx, "no camunda_wrapper_url configured")
 		return
 	}
-	req, err := http.NewRequest("GET", this.config.CamundaWrapperUrl+"/deployment?"+query.Encode(), nil)
-	if err != nil {
-		debug.PrintStack()
-		return nil, err
-	}
-	req.Header.Set("Authorization", token.Token)
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		log.Println("ERROR: GetProcessDeploymentList()::http.DefaultClient.Do(req)", err)
-		debug.PrintStack()
-		return result, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		responseMsg, _ := ioutil.ReadAll(resp.Body)
-		log.Println("ERROR: GetProcessDeploymentList(): unexpected response", resp.StatusCode, string(responseMsg))
-		debug.PrintStack()
-		return result, errors.New("unexpected response")
-	}
-	err = json.NewDecoder(resp.Body).Decode(&result)
-	if err != nil {
-		debug.PrintStack()
-	}
+	err = this.camundaWrapper.GetJson(ctx, token.Token, "/deployment?"+query.Encode(), &result)
 	return result, err
 }
 
-func (this *Lib) GetProcessDependencyList(token auth.Token, processIds []string) (result []Dependencies, err error) {
-	if this.Config().ProcessDeploymentUrl == "" || this.Config().ProcessDeploymentUrl == "-" {
-		log.Println("WARNING: no ProcessDeploymentUrl url configured")
+func (this *Lib) GetProcessDependencyList(ctx context.Context, token auth.Token, processIds []string) (result []Dependencies, err error) {
+	if !this.processDeployment.Enabled() {
+		slog.WarnContext(ctx, "no process_deployment_url configured")
 		return
 	}
-	err = GetJson(token.Token, this.config.ProcessDeploymentUrl+"/dependencies?ids="+strings.Join(processIds, ","), &result)
-	return
+	return batchList(ctx, this, processIds, func(ctx context.Context, ids []string) (result []Dependencies, err error) {
+		err = this.processDeployment.GetJson(ctx, token.Token, "/dependencies?ids="+strings.Join(ids, ","), &result)
+		return
+	})
 }
 
-func getOfflineReasons(metadata Dependencies) (result []OfflineReason, err error) {
+func getOfflineReasons(metadata Dependencies) (result []model.OfflineReason) {
 	for _, device := range metadata.Devices {
 		if !device.Online {
-			result = append(result, OfflineReason{
+			result = append(result, model.OfflineReason{
 				Type:           "device-offline",
 				Id:             device.DeviceId,
 				AdditionalInfo: map[string]interface{}{"name": device.Name, "tasks": device.BpmnResources},
@@ -124,7 +106,7 @@ func getOfflineReasons(metadata Dependencies) (result []OfflineReason, err error
 	}
 	for _, event := range metadata.Events {
 		if !event.Online {
-			result = append(result, OfflineReason{
+			result = append(result, model.OfflineReason{
 				Type:           "event-filter-offline",
 				Id:             event.EventId,
 				AdditionalInfo: map[string]interface{}{"tasks": event.BpmnResources},
@@ -6,6 +6,7 @@ import (
 	"github.com/SENERGY-Platform/api-aggregator/pkg"
 	"github.com/SENERGY-Platform/api-aggregator/pkg/api"
 	"github.com/SENERGY-Platform/api-aggregator/pkg/tests/environment"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/upstream"
 	"github.com/SENERGY-Platform/device-repository/lib/client"
 	"github.com/SENERGY-Platform/models/go/models"
 	"net"
@@ -126,7 +127,7 @@ func TestDevicesEndpoint(t *testing.T) {
 func testDeviceQuery(query string, port string, expected []models.Device) (string, func(t *testing.T)) {
 	return query, func(t *testing.T) {
 		result := []models.Device{}
-		err := pkg.GetJson(testjwt, "http://localhost:"+port+"/devices"+strings.ReplaceAll(query, ":", "%3A"), &result)
+		err := upstream.New("api-aggregator", "http://localhost:"+port, upstream.Options{}).GetJson(context.Background(), testjwt, "/devices"+strings.ReplaceAll(query, ":", "%3A"), &result)
 		if err != nil {
 			t.Error(err)
 			return
@@ -148,7 +149,7 @@ func testDeviceQuery(query string, port string, expected []models.Device) (strin
 func testDeviceQueryRaw(query string, port string, expected []models.Device) (string, func(t *testing.T)) {
 	return query, func(t *testing.T) {
 		result := []models.Device{}
-		err := pkg.GetJson(testjwt, "http://localhost:"+port+"/devices"+query, &result)
+		err := upstream.New("api-aggregator", "http://localhost:"+port, upstream.Options{}).GetJson(context.Background(), testjwt, "/devices"+query, &result)
 		if err != nil {
 			t.Error(err)
 			return
@@ -0,0 +1,1085 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/api-aggregator/pkg"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/api"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/client"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/tests/fakes"
+	"github.com/SENERGY-Platform/models/go/models"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newHermeticEnv starts the aggregator against fake upstreams seeded with testdata/fixtures.json
+func newHermeticEnv(t *testing.T, config pkg.Config) (upstreams *fakes.Upstreams, aggregatorUrl string) {
+	t.Helper()
+	fixtures, err := fakes.LoadFixtures("testdata/fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstreams = fakes.New(fixtures)
+	t.Cleanup(upstreams.Close)
+	server := httptest.NewServer(api.NewHandler(pkg.New(upstreams.Config(config))))
+	t.Cleanup(server.Close)
+	return upstreams, server.URL
+}
+
+func TestHermeticEndpoints(t *testing.T) {
+	upstreams, aggregatorUrl := newHermeticEnv(t, pkg.Config{PartialResponses: true})
+	c := client.NewClient(aggregatorUrl, nil)
+	ctx := context.Background()
+
+	t.Run("devices", func(t *testing.T) {
+		page, err, _ := c.ListDevices(ctx, testjwt, client.DeviceListOptions{Log: "1h"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		devices := page.Items
+		if len(devices) != 3 || devices[0].Name != "lamp" || devices[1].Name != "sensor" || devices[2].Name != "switch" {
+			t.Fatalf("%#v", devices)
+		}
+		if devices[0].LogState != model.LogStateConnected || devices[1].LogState != model.LogStateDisconnected || devices[2].LogState != model.LogStateUnknown {
+			t.Error(devices[0].LogState, devices[1].LogState, devices[2].LogState)
+		}
+		if devices[0].LogHistory == nil || len(devices[0].LogHistory.Values) != 1 || devices[0].LogEdge != true {
+			t.Errorf("%#v %#v", devices[0].LogHistory, devices[0].LogEdge)
+		}
+
+		page, err, _ = c.ListDevices(ctx, testjwt, client.DeviceListOptions{Limit: 1, Offset: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		devices = page.Items
+		if len(devices) != 1 || devices[0].Name != "sensor" || devices[0].LogHistory != nil {
+			t.Errorf("%#v", devices)
+		}
+	})
+
+	t.Run("hubs", func(t *testing.T) {
+		page, err, _ := c.ListHubs(ctx, testjwt, client.HubListOptions{Log: "1h"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hubs := page.Items
+		if len(hubs) != 2 || hubs[0].Name != "home" || hubs[1].Name != "office" {
+			t.Fatalf("%#v", hubs)
+		}
+		if hubs[0].LogState != model.LogStateConnected || hubs[1].LogState != model.LogStateDisconnected {
+			t.Error(hubs[0].LogState, hubs[1].LogState)
+		}
+		if hubs[0].LogHistory == nil || hubs[0].LogEdge != true {
+			t.Errorf("%#v %#v", hubs[0].LogHistory, hubs[0].LogEdge)
+		}
+	})
+
+	t.Run("processes", func(t *testing.T) {
+		processes, err, _ := c.ListProcesses(ctx, testjwt, url.Values{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(processes) != 2 {
+			t.Fatalf("%#v", processes)
+		}
+		online, offline := processes[0], processes[1]
+		if online.Id != "deployment-1" || online.OnlineState != model.OnlineStateOnline || online.Online == nil || !*online.Online {
+			t.Errorf("%#v", online)
+		}
+		if offline.Id != "deployment-2" || offline.OnlineState != model.OnlineStateOffline || len(offline.OfflineReasons) != 1 || offline.OfflineReasons[0].Id != "urn:ses:device:d2" {
+			t.Errorf("%#v", offline)
+		}
+	})
+
+	t.Run("aspect nodes", func(t *testing.T) {
+		nodes, err, _ := c.GetAspectNodesWithMeasuringFunction(ctx, testjwt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(nodes) != 1 || nodes[0].Id != "urn:ses:aspect:air" {
+			t.Errorf("%#v", nodes)
+		}
+	})
+
+	t.Run("measuring functions", func(t *testing.T) {
+		functions, err, _ := c.GetMeasuringFunctionsForAspect(ctx, testjwt, "urn:ses:aspect:air")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(functions) != 1 || functions[0].Id != "urn:ses:function:get-temperature" {
+			t.Errorf("%#v", functions)
+		}
+	})
+
+	t.Run("degraded connection-log", func(t *testing.T) {
+		upstreams.Fail(pkg.UpstreamConnectionLog, http.StatusInternalServerError)
+		defer upstreams.Fail(pkg.UpstreamConnectionLog, 0)
+		req, err := http.NewRequest(http.MethodGet, aggregatorUrl+"/v2/devices?log=1h", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", testjwt)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.Header.Get(api.WarningsHeader) == "" {
+			t.Error(resp.StatusCode, resp.Header.Get(api.WarningsHeader))
+		}
+	})
+}
+
+func TestHermeticCaching(t *testing.T) {
+	upstreams, aggregatorUrl := newHermeticEnv(t, pkg.Config{AspectCacheTtl: "1m", FunctionCacheTtl: "1m", ImportTypeCacheTtl: "1m"})
+	c := client.NewClient(aggregatorUrl, nil)
+	ctx := context.Background()
+
+	load := func() {
+		_, err, _ := c.GetAspectNodesWithMeasuringFunction(ctx, testjwt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err, _ = c.GetMeasuringFunctionsForAspect(ctx, testjwt, "urn:ses:aspect:air")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	load()
+	iotRequests, importRequests := upstreams.Requests(pkg.UpstreamIot), upstreams.Requests(pkg.UpstreamImportRepo)
+	load()
+	load()
+	if upstreams.Requests(pkg.UpstreamIot) != iotRequests || upstreams.Requests(pkg.UpstreamImportRepo) != importRequests {
+		t.Error("cached requests should not reach the upstreams", iotRequests, upstreams.Requests(pkg.UpstreamIot), importRequests, upstreams.Requests(pkg.UpstreamImportRepo))
+	}
+}
+
+func TestHermeticCacheInvalidation(t *testing.T) {
+	fixtures, err := fakes.LoadFixtures("testdata/fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstreams := fakes.New(fixtures)
+	defer upstreams.Close()
+	lib := pkg.New(upstreams.Config(pkg.Config{FunctionCacheTtl: "1h", AspectCacheTtl: "1h", FunctionTopic: "functions", DeviceTypeTopic: "device-types"}))
+	broker := fakes.NewBroker()
+	err = lib.StartCacheInvalidation(context.Background(), broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.NewHandler(lib))
+	defer server.Close()
+	c := client.NewClient(server.URL, nil)
+
+	getName := func() string {
+		functions, err, _ := c.GetMeasuringFunctionsForAspect(context.Background(), testjwt, "urn:ses:aspect:air")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(functions) != 1 {
+			t.Fatalf("%#v", functions)
+		}
+		return functions[0].Name
+	}
+	if name := getName(); name != "Get Temperature" {
+		t.Fatal(name)
+	}
+	upstreams.Update(func(fixtures *fakes.Fixtures) {
+		function := fixtures.MeasuringFunctions["urn:ses:aspect:air"][0]
+		function.Name = "Get Air Temperature"
+		fixtures.MeasuringFunctions["urn:ses:aspect:air"] = []model.MeasuringFunction{function}
+	})
+	if name := getName(); name != "Get Temperature" {
+		t.Error("expected cached function", name)
+	}
+	broker.Publish("functions", []byte(`{"command":"PUT","id":"urn:ses:function:get-temperature"}`))
+	if name := getName(); name != "Get Air Temperature" {
+		t.Error("expected invalidated cache", name)
+	}
+
+	//the aspects in use depend on the device types
+	countNodes := func() int {
+		nodes, err, _ := c.GetAspectNodesWithMeasuringFunction(context.Background(), testjwt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(nodes)
+	}
+	count := countNodes()
+	upstreams.Update(func(fixtures *fakes.Fixtures) {
+		fixtures.DeviceAspectNodes = append(slices.Clone(fixtures.DeviceAspectNodes), "urn:ses:aspect:inside-air")
+	})
+	if countNodes() != count {
+		t.Error("expected cached aspect nodes")
+	}
+	broker.Publish("device-types", []byte(`{"command":"PUT","id":"urn:ses:device-type:dt1"}`))
+	if newCount := countNodes(); newCount != count+1 {
+		t.Error("expected invalidated aspect nodes", count, newCount)
+	}
+}
+
+func TestHermeticCacheInvalidationDuringLoad(t *testing.T) {
+	fixtures, err := fakes.LoadFixtures("testdata/fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstreams := fakes.New(fixtures)
+	defer upstreams.Close()
+	arrived, release := upstreams.Pause(pkg.UpstreamIot)
+	defer release()
+	lib := pkg.New(upstreams.Config(pkg.Config{FunctionCacheTtl: "1h", FunctionTopic: "functions"}))
+	broker := fakes.NewBroker()
+	err = lib.StartCacheInvalidation(context.Background(), broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.NewHandler(lib))
+	defer server.Close()
+	c := client.NewClient(server.URL, nil)
+	getName := func() string {
+		functions, err, _ := c.GetMeasuringFunctionsForAspect(context.Background(), testjwt, "urn:ses:aspect:air")
+		if err != nil || len(functions) != 1 {
+			t.Errorf("%#v %v", functions, err)
+			return ""
+		}
+		return functions[0].Name
+	}
+
+	loaded := make(chan string)
+	go func() {
+		loaded <- getName()
+	}()
+	<-arrived //the load has read the old function and waits for the response
+	upstreams.Update(func(fixtures *fakes.Fixtures) {
+		function := fixtures.MeasuringFunctions["urn:ses:aspect:air"][0]
+		function.Name = "Get Air Temperature"
+		fixtures.MeasuringFunctions["urn:ses:aspect:air"] = []model.MeasuringFunction{function}
+	})
+	broker.Publish("functions", []byte(`{"command":"PUT","id":"urn:ses:function:get-temperature"}`))
+	release()
+	if name := <-loaded; name != "Get Temperature" {
+		t.Error(name)
+	}
+	if name := getName(); name != "Get Air Temperature" {
+		t.Error("the load running during the invalidation should not be cached", name)
+	}
+}
+
+func TestHermeticCoalescingAfterInvalidation(t *testing.T) {
+	fixtures, err := fakes.LoadFixtures("testdata/fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstreams := fakes.New(fixtures)
+	defer upstreams.Close()
+	arrived, release := upstreams.Pause(pkg.UpstreamIot)
+	defer release()
+	lib := pkg.New(upstreams.Config(pkg.Config{FunctionCacheTtl: "1h", FunctionTopic: "functions"}))
+	broker := fakes.NewBroker()
+	err = lib.StartCacheInvalidation(context.Background(), broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.NewHandler(lib))
+	defer server.Close()
+	c := client.NewClient(server.URL, nil)
+	getName := func() string {
+		functions, err, _ := c.GetMeasuringFunctionsForAspect(context.Background(), testjwt, "urn:ses:aspect:air")
+		if err != nil || len(functions) != 1 {
+			t.Errorf("%#v %v", functions, err)
+			return ""
+		}
+		return functions[0].Name
+	}
+
+	before := make(chan string)
+	go func() {
+		before <- getName()
+	}()
+	<-arrived //the load has read the old function and waits for the response
+	upstreams.Update(func(fixtures *fakes.Fixtures) {
+		function := fixtures.MeasuringFunctions["urn:ses:aspect:air"][0]
+		function.Name = "Get Air Temperature"
+		fixtures.MeasuringFunctions["urn:ses:aspect:air"] = []model.MeasuringFunction{function}
+	})
+	broker.Publish("functions", []byte(`{"command":"PUT","id":"urn:ses:function:get-temperature"}`))
+
+	//a caller after the invalidation does not join the load started before it
+	after := make(chan string)
+	go func() {
+		after <- getName()
+	}()
+	awaitRequests(t, arrived, 1) //the call after the invalidation loads the function again
+	release()
+	if name := <-before; name != "Get Temperature" {
+		t.Error(name)
+	}
+	if name := <-after; name != "Get Air Temperature" {
+		t.Error(name)
+	}
+	if name := getName(); name != "Get Air Temperature" {
+		t.Error("the outdated load should not be cached", name)
+	}
+}
+
+func TestHermeticCoalescing(t *testing.T) {
+	fixtures, err := fakes.LoadFixtures("testdata/fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstreams := fakes.New(fixtures)
+	defer upstreams.Close()
+	arrived, release := upstreams.Pause(pkg.UpstreamIot)
+	defer release()
+	handler := api.NewHandler(pkg.New(upstreams.Config(pkg.Config{})))
+	received := make(chan struct{}, 5)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/device-class-uses" {
+			received <- struct{}{}
+		}
+		handler.ServeHTTP(writer, request)
+	}))
+	defer server.Close()
+	aggregatorUrl := server.URL
+	c := client.NewClient(aggregatorUrl, nil)
+
+	wg := sync.WaitGroup{}
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err, _ := c.GetDeviceClassUses(context.Background(), testjwt)
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	//the first upstream request is held until all calls are received
+	awaitRequests(t, received, 5)
+	awaitRequests(t, arrived, 1)
+	release()
+	wg.Wait()
+	//one /extended-devices and one /v2/device-classes request for all calls
+	if requests := upstreams.Requests(pkg.UpstreamIot); requests != 2 {
+		t.Error(requests)
+	}
+
+	resp, err := http.Get(aggregatorUrl + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	metrics, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(metrics), `aggregator_coalesced_calls_total{operation="device-class-uses"} 4`) {
+		t.Error(string(metrics))
+	}
+}
+
+func TestHermeticRequestMetrics(t *testing.T) {
+	_, aggregatorUrl := newHermeticEnv(t, pkg.Config{})
+	c := client.NewClient(aggregatorUrl, nil)
+	ctx := context.Background()
+
+	//path segments equal to a parameter value must not be mistaken for the parameter
+	_, _, code := c.GetDevice(ctx, testjwt, "devices", client.DeviceOptions{})
+	if code != http.StatusNotFound {
+		t.Error(code)
+	}
+	_, _, code = c.GetMeasuringFunctionsForAspect(ctx, testjwt, "aspects")
+	if code != http.StatusBadGateway {
+		t.Error(code)
+	}
+	resp, err := http.Get(aggregatorUrl + "/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(aggregatorUrl + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	metrics, _ := io.ReadAll(resp.Body)
+	for _, expected := range []string{
+		`aggregator_http_requests_total{method="GET",route="/devices/:id",status="404"} 1`,
+		`aggregator_http_requests_total{method="GET",route="/aspects/:id/measuring-functions",status="502"} 1`,
+		`aggregator_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+	} {
+		if !strings.Contains(string(metrics), expected) {
+			t.Error(expected, string(metrics))
+		}
+	}
+}
+
+func TestHermeticErrorResponseStatus(t *testing.T) {
+	accessLog, serverLog := &lockedBuffer{}, &lockedBuffer{}
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(accessLog, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	fixtures, err := fakes.LoadFixtures("testdata/fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstreams := fakes.New(fixtures)
+	t.Cleanup(upstreams.Close)
+	server := httptest.NewUnstartedServer(api.NewHandler(pkg.New(upstreams.Config(pkg.Config{}))))
+	server.Config.ErrorLog = log.New(serverLog, "", 0)
+	server.Start()
+	t.Cleanup(server.Close)
+
+	_, _, code := client.NewClient(server.URL, nil).GetDevice(context.Background(), testjwt, "urn:ses:device:unknown", client.DeviceOptions{})
+	if code != http.StatusNotFound {
+		t.Error(code)
+	}
+	server.Close()
+	if !strings.Contains(accessLog.String(), "response-status-code=404") {
+		t.Error(accessLog.String())
+	}
+	if strings.Contains(serverLog.String(), "superfluous") {
+		t.Error(serverLog.String())
+	}
+}
+
+type lockedBuffer struct {
+	mux    sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (this *lockedBuffer) Write(p []byte) (int, error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return this.buffer.Write(p)
+}
+
+func (this *lockedBuffer) String() string {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return this.buffer.String()
+}
+
+func TestHermeticParallelFanOut(t *testing.T) {
+	upstreams, aggregatorUrl := newHermeticEnv(t, pkg.Config{UpstreamConcurrency: 2})
+	c := client.NewClient(aggregatorUrl, nil)
+
+	//log history and log edges are requested concurrently: both requests arrive before the first is answered
+	arrived, release := upstreams.Pause(pkg.UpstreamConnectionLog)
+	defer release()
+	done := make(chan error)
+	go func() {
+		_, err, _ := c.ListDevices(context.Background(), testjwt, client.DeviceListOptions{Log: "1h"})
+		done <- err
+	}()
+	awaitRequests(t, arrived, 2)
+	release()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	//device states and event states are requested concurrently
+	deviceStates, releaseDeviceStates := upstreams.Pause(pkg.UpstreamConnectionLog)
+	defer releaseDeviceStates()
+	eventStates, releaseEventStates := upstreams.Pause(pkg.UpstreamEventManager)
+	defer releaseEventStates()
+	go func() {
+		_, err, _ := c.ListProcesses(context.Background(), testjwt, url.Values{})
+		done <- err
+	}()
+	awaitRequests(t, deviceStates, 1)
+	awaitRequests(t, eventStates, 1)
+	releaseDeviceStates()
+	releaseEventStates()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// awaitRequests waits for count requests signaled by arrived (e.g. of fakes.Upstreams.Pause);
+// requests that are not sent concurrently never arrive while the previous ones are held
+func awaitRequests(t *testing.T, arrived <-chan struct{}, count int) {
+	t.Helper()
+	for i := range count {
+		select {
+		case <-arrived:
+		case <-time.After(5 * time.Second):
+			t.Errorf("only %v of %v requests are in flight at the same time", i, count)
+			return
+		}
+	}
+}
+
+func TestHermeticIdBatching(t *testing.T) {
+	upstreams, aggregatorUrl := newHermeticEnv(t, pkg.Config{IdBatchSize: 1})
+	c := client.NewClient(aggregatorUrl, nil)
+
+	page, err, _ := c.ListDevices(context.Background(), testjwt, client.DeviceListOptions{Log: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	devices := page.Items
+	if len(devices) != 3 || devices[0].LogHistory == nil || len(devices[0].LogHistory.Values) != 1 || devices[0].LogEdge != true {
+		t.Errorf("%#v", devices)
+	}
+	//one history and one edge request per device
+	if requests := upstreams.Requests(pkg.UpstreamConnectionLog); requests != 6 {
+		t.Error(requests)
+	}
+
+	processes, err, _ := c.ListProcesses(context.Background(), testjwt, url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(processes) != 2 || processes[0].OnlineState != model.OnlineStateOnline || processes[1].OnlineState != model.OnlineStateOffline {
+		t.Errorf("%#v", processes)
+	}
+
+	upstreams.Fail(pkg.UpstreamConnectionLog, http.StatusInternalServerError)
+	_, err, _ = c.ListProcesses(context.Background(), testjwt, url.Values{})
+	if err == nil || !strings.Contains(err.Error(), "of 2 (1 ids starting with urn:ses:device:d") {
+		t.Error(err)
+	}
+}
+
+func TestHermeticCursorPagination(t *testing.T) {
+	upstreams, aggregatorUrl := newHermeticEnv(t, pkg.Config{})
+	c := client.NewClient(aggregatorUrl, nil)
+	ctx := context.Background()
+
+	page, err, _ := c.ListDevices(ctx, testjwt, client.DeviceListOptions{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, device := range page.Items {
+		names = append(names, device.Name)
+	}
+
+	//a device inserted before the cursor and a renamed device are neither duplicated nor skipped
+	upstreams.Update(func(fixtures *fakes.Fixtures) {
+		device := fixtures.Devices[0]
+		device.Id, device.Name = "urn:ses:device:d0", "aaa"
+		fixtures.Devices = append(fixtures.Devices, device)
+		for i := range fixtures.Devices {
+			if fixtures.Devices[i].Name == "sensor" {
+				fixtures.Devices[i].Name = "sensor2"
+			}
+		}
+	})
+	for page.NextCursor != "" {
+		page, err, _ = c.ListDevices(ctx, testjwt, client.DeviceListOptions{Limit: 1, Cursor: page.NextCursor})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, device := range page.Items {
+			names = append(names, device.Name)
+		}
+	}
+	if strings.Join(names, ",") != "lamp,sensor2,switch" {
+		t.Error(names)
+	}
+
+	prev, err, _ := c.ListDevices(ctx, testjwt, client.DeviceListOptions{Limit: 1, Cursor: page.PrevCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prev.Items) != 1 || prev.Items[0].Name != "sensor2" {
+		t.Errorf("%#v", prev.Items)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, aggregatorUrl+"/devices?limit=1&offset=1", nil)
+	req.Header.Set("Authorization", testjwt)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if link := resp.Header.Get("Link"); !strings.Contains(link, `rel="next"`) || !strings.Contains(link, `rel="prev"`) || strings.Contains(link, "offset=") {
+		t.Error(link)
+	}
+
+	_, _, code := c.ListDevices(ctx, testjwt, client.DeviceListOptions{Limit: 1, Cursor: "invalid"})
+	if code != http.StatusBadRequest {
+		t.Error(code)
+	}
+}
+
+func TestHermeticCursorPaginationTies(t *testing.T) {
+	upstreams, aggregatorUrl := newHermeticEnv(t, pkg.Config{})
+	c := client.NewClient(aggregatorUrl, nil)
+	ctx := context.Background()
+
+	upstreams.Update(func(fixtures *fakes.Fixtures) {
+		for _, id := range []string{"urn:ses:device:d1b", "urn:ses:device:d1a"} {
+			device := fixtures.Devices[0]
+			device.Id = id
+			fixtures.Devices = append(fixtures.Devices, device)
+		}
+	})
+	page, err, _ := c.ListDevices(ctx, testjwt, client.DeviceListOptions{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, device := range page.Items {
+		ids = append(ids, device.Id)
+	}
+
+	//devices with the name of a removed anchor are neither duplicated nor skipped
+	upstreams.Update(func(fixtures *fakes.Fixtures) {
+		fixtures.Devices = slices.DeleteFunc(fixtures.Devices, func(device models.ExtendedDevice) bool {
+			return device.Id == "urn:ses:device:d1"
+		})
+	})
+	for page.NextCursor != "" {
+		page, err, _ = c.ListDevices(ctx, testjwt, client.DeviceListOptions{Limit: 1, Cursor: page.NextCursor})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, device := range page.Items {
+			ids = append(ids, device.Id)
+		}
+	}
+	if strings.Join(ids, ",") != "urn:ses:device:d1,urn:ses:device:d1b,urn:ses:device:d1a,urn:ses:device:d2,urn:ses:device:d3" {
+		t.Error(ids)
+	}
+
+	page, err, _ = c.ListDevices(ctx, testjwt, client.DeviceListOptions{Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstreams.Update(func(fixtures *fakes.Fixtures) {
+		fixtures.Devices = slices.DeleteFunc(fixtures.Devices, func(device models.ExtendedDevice) bool {
+			return device.Id == "urn:ses:device:d1a"
+		})
+	})
+	prev, err, _ := c.ListDevices(ctx, testjwt, client.DeviceListOptions{Limit: 1, Cursor: page.PrevCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prev.Items) != 1 || prev.Items[0].Id != "urn:ses:device:d1b" {
+		t.Errorf("%#v", prev.Items)
+	}
+
+	//most devices share a log_state
+	page, err, _ = c.ListDevices(ctx, testjwt, client.DeviceListOptions{Limit: 1, Sort: "log_state.asc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.NextCursor != "" || !page.HasMore {
+		t.Errorf("%#v", page)
+	}
+	_, _, code := c.ListDevices(ctx, testjwt, client.DeviceListOptions{Limit: 1, Sort: "log_state.asc", Cursor: prev.NextCursor})
+	if code != http.StatusBadRequest {
+		t.Error(code)
+	}
+}
+
+func TestHermeticTotalCount(t *testing.T) {
+	_, aggregatorUrl := newHermeticEnv(t, pkg.Config{})
+	c := client.NewClient(aggregatorUrl, nil)
+	ctx := context.Background()
+
+	page, err, _ := c.ListDevices(ctx, testjwt, client.DeviceListOptions{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.Total != 3 || page.Limit != 2 || page.Offset != 0 || !page.HasMore {
+		t.Errorf("%#v", page)
+	}
+	page, err, _ = c.ListDevices(ctx, testjwt, client.DeviceListOptions{Limit: 2, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Total != 3 || page.Offset != 2 || page.HasMore || page.NextCursor != "" {
+		t.Errorf("%#v", page)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, aggregatorUrl+"/hubs?limit=1", nil)
+	req.Header.Set("Authorization", testjwt)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if total := resp.Header.Get("X-Total-Count"); total != "2" {
+		t.Error(total)
+	}
+}
+
+func TestHermeticDeviceFilters(t *testing.T) {
+	upstreams, aggregatorUrl := newHermeticEnv(t, pkg.Config{})
+	upstreams.Update(func(fixtures *fakes.Fixtures) {
+		fixtures.Devices[0].Attributes = []models.Attribute{{Key: "room", Value: "kitchen"}}
+		fixtures.Devices[2].Attributes = []models.Attribute{{Key: "room", Value: "office"}}
+		fixtures.Devices[1].OwnerId = "other-user"
+	})
+	c := client.NewClient(aggregatorUrl, nil)
+	ctx := context.Background()
+
+	for name, test := range map[string]struct {
+		options client.DeviceListOptions
+		names   string
+	}{
+		"sort":                 {options: client.DeviceListOptions{Sort: "name.desc"}, names: "switch,sensor,lamp"},
+		"search":               {options: client.DeviceListOptions{Search: "s"}, names: "sensor,switch"},
+		"device type":          {options: client.DeviceListOptions{DeviceTypeIds: []string{"urn:ses:device-type:dt1"}}, names: "lamp,switch"},
+		"offline of class":     {options: client.DeviceListOptions{DeviceClassIds: []string{"urn:ses:device-class:sensor"}, LogState: model.LogStateDisconnected}, names: "sensor"},
+		"unknown state":        {options: client.DeviceListOptions{LogState: model.LogStateUnknown}, names: "switch"},
+		"attribute":            {options: client.DeviceListOptions{Attributes: map[string]string{"room": "kitchen"}}, names: "lamp"},
+		"owner":                {options: client.DeviceListOptions{Owner: "other-user"}, names: "sensor"},
+		"owner page":           {options: client.DeviceListOptions{Owner: "test-user", Limit: 1, Offset: 1}, names: "switch"},
+		"class page":           {options: client.DeviceListOptions{DeviceClassIds: []string{"urn:ses:device-class:lamp"}, Limit: 1, Offset: 1}, names: "switch"},
+		"class sorted desc":    {options: client.DeviceListOptions{DeviceClassIds: []string{"urn:ses:device-class:lamp"}, Sort: "name.desc"}, names: "switch,lamp"},
+		"search and log state": {options: client.DeviceListOptions{Search: "s", LogState: model.LogStateDisconnected}, names: "sensor"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			page, err, _ := c.ListDevices(ctx, testjwt, test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, device := range page.Items {
+				names = append(names, device.Name)
+			}
+			if strings.Join(names, ",") != test.names {
+				t.Error(names)
+			}
+		})
+	}
+
+	//totals and cursors of filters applied by the aggregator
+	page, err, _ := c.ListDevices(ctx, testjwt, client.DeviceListOptions{DeviceClassIds: []string{"urn:ses:device-class:lamp"}, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Total != 2 || !page.HasMore {
+		t.Errorf("%#v", page)
+	}
+	page, err, _ = c.ListDevices(ctx, testjwt, client.DeviceListOptions{DeviceClassIds: []string{"urn:ses:device-class:lamp"}, Limit: 1, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Name != "switch" || page.HasMore {
+		t.Errorf("%#v", page)
+	}
+
+	_, _, code := c.ListDevices(ctx, testjwt, client.DeviceListOptions{Sort: "owner.asc"})
+	if code != http.StatusBadRequest {
+		t.Error(code)
+	}
+}
+
+func TestHermeticHubFilters(t *testing.T) {
+	_, aggregatorUrl := newHermeticEnv(t, pkg.Config{})
+	c := client.NewClient(aggregatorUrl, nil)
+	ctx := context.Background()
+	var one int64 = 1
+
+	for name, test := range map[string]struct {
+		options client.HubListOptions
+		names   string
+	}{
+		"sort":        {options: client.HubListOptions{Sort: "name.desc"}, names: "office,home"},
+		"search":      {options: client.HubListOptions{Search: "off"}, names: "office"},
+		"log state":   {options: client.HubListOptions{LogState: model.LogStateConnected}, names: "home"},
+		"device":      {options: client.HubListOptions{DeviceId: "urn:ses:device:d3"}, names: "office"},
+		"min devices": {options: client.HubListOptions{MinDevices: 2}, names: "home"},
+		"max devices": {options: client.HubListOptions{MaxDevices: &one}, names: "office"},
+		"page":        {options: client.HubListOptions{MaxDevices: &one, Offset: 1}, names: ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			page, err, _ := c.ListHubs(ctx, testjwt, test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, hub := range page.Items {
+				names = append(names, hub.Name)
+			}
+			if strings.Join(names, ",") != test.names {
+				t.Error(names)
+			}
+		})
+	}
+
+	_, _, code := c.ListHubs(ctx, testjwt, client.HubListOptions{MinDevices: 2, MaxDevices: &one})
+	if code != http.StatusBadRequest {
+		t.Error(code)
+	}
+}
+
+func TestHermeticFieldsAndExpansions(t *testing.T) {
+	upstreams, aggregatorUrl := newHermeticEnv(t, pkg.Config{})
+	c := client.NewClient(aggregatorUrl, nil)
+	ctx := context.Background()
+
+	req, _ := http.NewRequest(http.MethodGet, aggregatorUrl+"/v2/devices?limit=1&fields=id,name,log_state", nil)
+	req.Header.Set("Authorization", testjwt)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sparse := model.Page[map[string]interface{}]{}
+	err = json.NewDecoder(resp.Body).Decode(&sparse)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sparse.Items) != 1 || len(sparse.Items[0]) != 3 || sparse.Items[0]["name"] != "lamp" || sparse.Total != 3 {
+		t.Errorf("%#v", sparse)
+	}
+
+	page, err, _ := c.ListDevices(ctx, testjwt, client.DeviceListOptions{Expand: []string{"device_class", "hub", "dependent_processes"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lamp := page.Items[0]
+	if lamp.DeviceType != nil || lamp.DeviceClass == nil || lamp.DeviceClass.Name != "Lamp" ||
+		!reflect.DeepEqual(lamp.Hubs, []model.HubReference{{Id: "urn:ses:hub:h1", Name: "home"}}) ||
+		!reflect.DeepEqual(lamp.DependentProcesses, []model.ProcessReference{{Id: "deployment-1", Name: "light on"}}) {
+		t.Errorf("%#v", lamp)
+	}
+	if switchDevice := page.Items[2]; switchDevice.DependentProcesses != nil || len(switchDevice.Hubs) != 1 || switchDevice.Hubs[0].Name != "office" {
+		t.Errorf("%#v", switchDevice)
+	}
+
+	//unrequested expansions are not requested upstream
+	page, err, _ = c.ListDevices(ctx, testjwt, client.DeviceListOptions{Log: "1h", Expand: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Items[0].DeviceType != nil || page.Items[0].LogHistory != nil || page.Items[0].Hubs != nil {
+		t.Errorf("%#v", page.Items[0])
+	}
+	if requests := upstreams.Requests(pkg.UpstreamConnectionLog); requests != 0 {
+		t.Error(requests)
+	}
+
+	page, err, _ = c.ListDevices(ctx, testjwt, client.DeviceListOptions{Log: "1h", Expand: []string{"log_edge"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Items[0].LogHistory != nil || page.Items[0].LogEdge != true {
+		t.Errorf("%#v", page.Items[0])
+	}
+	if requests := upstreams.Requests(pkg.UpstreamConnectionLog); requests != 1 {
+		t.Error(requests)
+	}
+
+	_, _, code := c.ListDevices(ctx, testjwt, client.DeviceListOptions{Expand: []string{"log_history"}})
+	if code != http.StatusBadRequest {
+		t.Error(code)
+	}
+	_, _, code = c.ListDevices(ctx, testjwt, client.DeviceListOptions{Expand: []string{"unknown"}})
+	if code != http.StatusBadRequest {
+		t.Error(code)
+	}
+}
+
+func TestHermeticDeviceLookup(t *testing.T) {
+	upstreams, aggregatorUrl := newHermeticEnv(t, pkg.Config{MaxLookupIds: 5})
+	c := client.NewClient(aggregatorUrl, nil)
+	ctx := context.Background()
+
+	result, err, _ := c.QueryDevices(ctx, testjwt, model.DeviceLookup{
+		Ids: []string{"urn:ses:device:d3", "urn:ses:device:d1", "urn:ses:device:d9", "urn:ses:device:unknown", "urn:ses:device:d1"},
+		Log: "1h",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Devices) != 2 || result.Devices[0].Name != "switch" || result.Devices[1].Name != "lamp" ||
+		result.Devices[1].LogHistory == nil || len(result.Devices[1].LogHistory.Values) != 1 || result.Devices[1].DeviceType == nil {
+		t.Errorf("%#v", result.Devices)
+	}
+	if len(result.Errors) != 2 ||
+		result.Errors[0].Id != "urn:ses:device:d9" || result.Errors[0].StatusCode != http.StatusForbidden ||
+		result.Errors[1].Id != "urn:ses:device:unknown" || result.Errors[1].StatusCode != http.StatusNotFound {
+		t.Errorf("%#v", result.Errors)
+	}
+
+	result, err, _ = c.QueryDevices(ctx, testjwt, model.DeviceLookup{Ids: []string{"urn:ses:device:d2"}, Expand: []string{"device_class"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Devices) != 1 || result.Devices[0].DeviceClass == nil || result.Devices[0].DeviceClass.Name != "Sensor" || result.Devices[0].DeviceType != nil || len(result.Errors) != 0 {
+		t.Errorf("%#v", result)
+	}
+
+	_, _, code := c.QueryDevices(ctx, testjwt, model.DeviceLookup{Ids: []string{"urn:ses:device:d2"}, Expand: []string{"log_edge"}})
+	if code != http.StatusBadRequest {
+		t.Error(code)
+	}
+
+	//more than max_lookup_ids ids are rejected before any upstream request
+	iotRequests := upstreams.Requests(pkg.UpstreamIot)
+	_, _, code = c.QueryDevices(ctx, testjwt, model.DeviceLookup{Ids: []string{"a", "b", "c", "d", "e", "f"}})
+	if code != http.StatusBadRequest || upstreams.Requests(pkg.UpstreamIot) != iotRequests {
+		t.Error(code, iotRequests, upstreams.Requests(pkg.UpstreamIot))
+	}
+}
+
+func TestHermeticDeviceLookupAboveDefaultLimit(t *testing.T) {
+	upstreams, aggregatorUrl := newHermeticEnv(t, pkg.Config{})
+	c := client.NewClient(aggregatorUrl, nil)
+
+	//the device-repository lists 100 devices without limit
+	ids := []string{}
+	upstreams.Update(func(fixtures *fakes.Fixtures) {
+		for i := range 150 {
+			device := fixtures.Devices[0]
+			device.Id = "urn:ses:device:many-" + strconv.Itoa(i)
+			fixtures.Devices = append(fixtures.Devices, device)
+			ids = append(ids, device.Id)
+		}
+	})
+	iotRequests := upstreams.Requests(pkg.UpstreamIot)
+	result, err, _ := c.QueryDevices(context.Background(), testjwt, model.DeviceLookup{Ids: ids})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Devices) != 150 || len(result.Errors) != 0 {
+		t.Error(len(result.Devices), result.Errors)
+	}
+	//one id-filtered device list and no single device reads
+	if requests := upstreams.Requests(pkg.UpstreamIot) - iotRequests; requests != 1 {
+		t.Error(requests)
+	}
+}
+
+func TestHermeticDeviceDetail(t *testing.T) {
+	_, aggregatorUrl := newHermeticEnv(t, pkg.Config{})
+	c := client.NewClient(aggregatorUrl, nil)
+	ctx := context.Background()
+
+	device, err, _ := c.GetDevice(ctx, testjwt, "urn:ses:device:d1", client.DeviceOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.Name != "lamp" || device.LogState != model.LogStateConnected || device.DeviceType == nil ||
+		device.LogHistory == nil || len(device.LogHistory.Values) != 1 || device.LogEdge != true || device.LogStart != "2024-12-31T00:00:00Z" ||
+		device.DeviceClass == nil || device.DeviceClass.Name != "Lamp" ||
+		!reflect.DeepEqual(device.Hubs, []model.HubReference{{Id: "urn:ses:hub:h1", Name: "home"}}) ||
+		!reflect.DeepEqual(device.DependentProcesses, []model.ProcessReference{{Id: "deployment-1", Name: "light on"}}) {
+		t.Errorf("%#v", device)
+	}
+
+	device, err, _ = c.GetDevice(ctx, testjwt, "urn:ses:device:d2", client.DeviceOptions{Expand: []string{"hub"}, Fields: []string{"id", "hubs"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.Id != "urn:ses:device:d2" || device.Name != "" || device.DeviceType != nil || device.LogHistory != nil ||
+		!reflect.DeepEqual(device.Hubs, []model.HubReference{{Id: "urn:ses:hub:h1", Name: "home"}}) {
+		t.Errorf("%#v", device)
+	}
+
+	_, _, code := c.GetDevice(ctx, testjwt, "urn:ses:device:d9", client.DeviceOptions{})
+	if code != http.StatusForbidden {
+		t.Error(code)
+	}
+	_, _, code = c.GetDevice(ctx, testjwt, "urn:ses:device:unknown", client.DeviceOptions{})
+	if code != http.StatusNotFound {
+		t.Error(code)
+	}
+	_, _, code = c.GetDevice(ctx, testjwt, "urn:ses:device:d1", client.DeviceOptions{Expand: []string{"unknown"}})
+	if code != http.StatusBadRequest {
+		t.Error(code)
+	}
+}
+
+func TestHermeticHubDetail(t *testing.T) {
+	_, aggregatorUrl := newHermeticEnv(t, pkg.Config{})
+	c := client.NewClient(aggregatorUrl, nil)
+	ctx := context.Background()
+
+	hub, err, _ := c.GetHub(ctx, testjwt, "urn:ses:hub:h1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hub.Name != "home" || hub.LogState != model.LogStateConnected || hub.LogHistory == nil || len(hub.LogHistory.Values) != 1 || hub.LogEdge != true {
+		t.Errorf("%#v", hub)
+	}
+	if len(hub.Devices) != 2 ||
+		hub.Devices[0].Name != "lamp" || hub.Devices[0].LogState != model.LogStateConnected || hub.Devices[0].LogHistory == nil || hub.Devices[0].LogEdge != true ||
+		hub.Devices[1].Name != "sensor" || hub.Devices[1].LogState != model.LogStateDisconnected {
+		t.Errorf("%#v", hub.Devices)
+	}
+
+	hub, err, _ = c.GetHub(ctx, testjwt, "urn:ses:hub:h2", "1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hub.LogState != model.LogStateDisconnected || len(hub.Devices) != 1 || hub.Devices[0].Name != "switch" || hub.Devices[0].LogState != model.LogStateUnknown {
+		t.Errorf("%#v", hub)
+	}
+
+	_, _, code := c.GetHub(ctx, testjwt, "urn:ses:hub:unknown", "")
+	if code != http.StatusNotFound {
+		t.Error(code)
+	}
+}
+
+func TestHermeticDependents(t *testing.T) {
+	_, aggregatorUrl := newHermeticEnv(t, pkg.Config{})
+	c := client.NewClient(aggregatorUrl, nil)
+	ctx := context.Background()
+
+	dependents, err, _ := c.GetDeviceDependents(ctx, testjwt, "urn:ses:device:d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []model.Dependent{{Id: "deployment-1", Name: "light on", Tasks: []model.BpmnTask{{Id: "Task_1", Label: "switch on"}}}}
+	if !reflect.DeepEqual(dependents, expected) {
+		t.Errorf("%#v", dependents)
+	}
+
+	result, err, _ := c.QueryDependents(ctx, testjwt, model.DependentsLookup{
+		DeviceIds: []string{"urn:ses:device:d2", "urn:ses:device:d3"},
+		EventIds:  []string{"event-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedResult := model.DependentsLookupResult{
+		Devices: map[string][]model.Dependent{
+			"urn:ses:device:d2": {{Id: "deployment-2", Name: "alarm", Tasks: []model.BpmnTask{{Id: "Task_2", Label: "read"}}}},
+			"urn:ses:device:d3": {},
+		},
+		Events: map[string][]model.Dependent{
+			"event-1": {{Id: "deployment-2", Name: "alarm", Tasks: []model.BpmnTask{{Id: "Event_1", Label: "alarm"}}}},
+		},
+	}
+	if !reflect.DeepEqual(result, expectedResult) {
+		t.Errorf("%#v", result)
+	}
+}
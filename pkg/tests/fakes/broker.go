@@ -0,0 +1,48 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"context"
+	"sync"
+)
+
+// Broker is an in-memory stand-in for kafka; Publish delivers synchronously to all subscribers of the topic
+type Broker struct {
+	mux      sync.Mutex
+	handlers map[string][]func(message []byte)
+}
+
+func NewBroker() *Broker {
+	return &Broker{handlers: map[string][]func(message []byte){}}
+}
+
+func (this *Broker) Subscribe(ctx context.Context, topic string, handler func(message []byte)) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.handlers[topic] = append(this.handlers[topic], handler)
+	return nil
+}
+
+func (this *Broker) Publish(topic string, message []byte) {
+	this.mux.Lock()
+	handlers := this.handlers[topic]
+	this.mux.Unlock()
+	for _, handler := range handlers {
+		handler(message)
+	}
+}
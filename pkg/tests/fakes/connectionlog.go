@@ -0,0 +1,110 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/api-aggregator/pkg"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+)
+
+func (this *Upstreams) connectionLog() http.Handler {
+	router := httprouter.New()
+	router.POST("/intern/state/:kind/check", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		ids, ok := readIds(writer, request)
+		if !ok {
+			return
+		}
+		writeJson(writer, read(this, func(fixtures *Fixtures) map[string]bool {
+			states := fixtures.DeviceStates
+			if params.ByName("kind") == "gateway" {
+				states = fixtures.HubStates
+			}
+			result := map[string]bool{}
+			for _, id := range ids {
+				result[id] = states[id]
+			}
+			return result
+		}))
+	})
+	router.POST("/intern/history/:kind/:duration", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		ids, ok := readIds(writer, request)
+		if !ok {
+			return
+		}
+		writeJson(writer, read(this, func(fixtures *Fixtures) []pkg.HistoryResult {
+			history := fixtures.DeviceHistory
+			if params.ByName("kind") == "gateway" {
+				history = fixtures.HubHistory
+			}
+			result := pkg.HistoryResult{Series: []model.HistorySeries{}}
+			for _, id := range ids {
+				if series, ok := history[id]; ok {
+					series.Tags = map[string]string{params.ByName("kind"): id}
+					result.Series = append(result.Series, series)
+				}
+			}
+			return []pkg.HistoryResult{result}
+		}))
+	})
+	router.POST("/intern/logedge/:kind/:duration", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		ids, ok := readIds(writer, request)
+		if !ok {
+			return
+		}
+		writeJson(writer, read(this, func(fixtures *Fixtures) map[string]interface{} {
+			if params.ByName("kind") == "gateway" {
+				return pick(fixtures.HubEdges, ids)
+			}
+			return pick(fixtures.DeviceEdges, ids)
+		}))
+	})
+	router.POST("/intern/logstarts/:kind", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		ids, ok := readIds(writer, request)
+		if !ok {
+			return
+		}
+		writeJson(writer, read(this, func(fixtures *Fixtures) map[string]interface{} {
+			if params.ByName("kind") == "gateway" {
+				return pick(fixtures.HubStarts, ids)
+			}
+			return pick(fixtures.DeviceStarts, ids)
+		}))
+	})
+	return router
+}
+
+func readIds(writer http.ResponseWriter, request *http.Request) (ids []string, ok bool) {
+	err := json.NewDecoder(request.Body).Decode(&ids)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return ids, true
+}
+
+func pick[T any](values map[string]T, ids []string) map[string]T {
+	result := map[string]T{}
+	for _, id := range ids {
+		if value, ok := values[id]; ok {
+			result[id] = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/api-aggregator/pkg"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func (this *Upstreams) deviceRepository() http.Handler {
+	router := httprouter.New()
+	router.GET("/extended-devices", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		devices := read(this, func(fixtures *Fixtures) []models.ExtendedDevice { return slices.Clone(fixtures.Devices) })
+		query := request.URL.Query()
+		devices = filter(devices, func(device models.ExtendedDevice) bool {
+			return matchesList(query.Get("device-type-ids"), device.DeviceTypeId) &&
+				matchesList(query.Get("local_ids"), device.LocalId) &&
+				(!query.Has("owner") || query.Get("owner") == device.OwnerId) &&
+				(!query.Has("connection-state") || query.Get("connection-state") == device.ConnectionState)
+		})
+		if query.Get("fulldt") == "true" {
+			deviceTypes := read(this, func(fixtures *Fixtures) []models.DeviceType { return slices.Clone(fixtures.DeviceTypes) })
+			for i, device := range devices {
+				index := slices.IndexFunc(deviceTypes, func(deviceType models.DeviceType) bool { return deviceType.Id == device.DeviceTypeId })
+				if index >= 0 {
+					devices[i].DeviceType = &deviceTypes[index]
+				}
+			}
+		}
+		writeList(writer, request, devices, func(device models.ExtendedDevice) (string, string) { return device.Id, device.Name })
+	})
+	router.GET("/extended-devices/:id", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		id := params.ByName("id")
+		matches := func(device models.ExtendedDevice) bool { return device.Id == id }
+		devices := read(this, func(fixtures *Fixtures) []models.ExtendedDevice { return filter(fixtures.Devices, matches) })
+		if len(devices) == 0 {
+			if read(this, func(fixtures *Fixtures) bool { return slices.ContainsFunc(fixtures.ForbiddenDevices, matches) }) {
+				http.Error(writer, "access denied", http.StatusForbidden)
+				return
+			}
+			http.Error(writer, "not found", http.StatusNotFound)
+			return
+		}
+		writeJson(writer, devices[0])
+	})
+	router.GET("/extended-hubs", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		hubs := read(this, func(fixtures *Fixtures) []models.ExtendedHub { return slices.Clone(fixtures.Hubs) })
+		query := request.URL.Query()
+		hubs = filter(hubs, func(hub models.ExtendedHub) bool {
+			return (!query.Has("connection-state") || query.Get("connection-state") == hub.ConnectionState) &&
+				(!query.Has("local-device-id") || slices.Contains(hub.DeviceLocalIds, query.Get("local-device-id")))
+		})
+		writeList(writer, request, hubs, func(hub models.ExtendedHub) (string, string) { return hub.Id, hub.Name })
+	})
+	router.GET("/extended-hubs/:id", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		hubs := read(this, func(fixtures *Fixtures) []models.ExtendedHub {
+			return filter(fixtures.Hubs, func(hub models.ExtendedHub) bool { return hub.Id == params.ByName("id") })
+		})
+		if len(hubs) == 0 {
+			http.Error(writer, "not found", http.StatusNotFound)
+			return
+		}
+		writeJson(writer, hubs[0])
+	})
+	router.GET("/functions", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		functions := read(this, func(fixtures *Fixtures) []models.Function { return slices.Clone(fixtures.Functions) })
+		writeList(writer, request, functions, func(function models.Function) (string, string) { return function.Id, function.Name })
+	})
+	router.GET("/v2/device-classes", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		deviceClasses := read(this, func(fixtures *Fixtures) []models.DeviceClass { return slices.Clone(fixtures.DeviceClasses) })
+		writeList(writer, request, deviceClasses, func(deviceClass models.DeviceClass) (string, string) { return deviceClass.Id, deviceClass.Name })
+	})
+	router.GET("/aspects/:id/measuring-functions", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		functions := read(this, func(fixtures *Fixtures) []model.MeasuringFunction {
+			return slices.Clone(fixtures.MeasuringFunctions[params.ByName("id")])
+		})
+		if functions == nil {
+			functions = []model.MeasuringFunction{}
+		}
+		writeJson(writer, functions)
+	})
+	router.GET("/aspect-nodes", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		if request.URL.Query().Get("function") != "measuring-function" {
+			http.Error(writer, "fake only supports function=measuring-function", http.StatusBadRequest)
+			return
+		}
+		writeJson(writer, read(this, func(fixtures *Fixtures) []model.AspectNode {
+			return filter(fixtures.AspectNodes, func(node model.AspectNode) bool { return slices.Contains(fixtures.DeviceAspectNodes, node.Id) })
+		}))
+	})
+	router.POST("/query/aspect-nodes", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		query := pkg.AspectNodeQuery{}
+		err := json.NewDecoder(request.Body).Decode(&query)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJson(writer, read(this, func(fixtures *Fixtures) []model.AspectNode {
+			return filter(fixtures.AspectNodes, func(node model.AspectNode) bool { return slices.Contains(query.Ids, node.Id) })
+		}))
+	})
+	return router
+}
+
+// writeList applies the ids, search, sort ("name.asc" or "name.desc"), limit and offset query parameters of the device-repository
+// and sets the X-Total-Count header
+func writeList[T any](writer http.ResponseWriter, request *http.Request, list []T, idAndName func(T) (string, string)) {
+	query := request.URL.Query()
+	list = filter(list, func(element T) bool {
+		id, name := idAndName(element)
+		return matchesList(query.Get("ids"), id) && strings.Contains(strings.ToLower(name), strings.ToLower(query.Get("search")))
+	})
+	slices.SortStableFunc(list, func(a T, b T) int {
+		_, nameA := idAndName(a)
+		_, nameB := idAndName(b)
+		if query.Get("sort") == "name.desc" {
+			return strings.Compare(nameB, nameA)
+		}
+		return strings.Compare(nameA, nameB)
+	})
+	total := len(list)
+	limit, offset := 100, 0
+	if query.Has("limit") {
+		limit, _ = strconv.Atoi(query.Get("limit"))
+	}
+	if query.Has("offset") {
+		offset, _ = strconv.Atoi(query.Get("offset"))
+	}
+	list = list[min(offset, len(list)):min(offset+limit, len(list))]
+	writer.Header().Set("X-Total-Count", strconv.Itoa(total))
+	writeJson(writer, list)
+}
+
+// matchesList returns true if list (comma separated) is empty or contains value
+func matchesList(list string, value string) bool {
+	return list == "" || slices.Contains(strings.Split(list, ","), value)
+}
+
+func filter[T any](list []T, keep func(T) bool) (result []T) {
+	result = []T{}
+	for _, element := range list {
+		if keep(element) {
+			result = append(result, element)
+		}
+	}
+	return result
+}
+
+func writeJson(writer http.ResponseWriter, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(writer).Encode(value)
+}
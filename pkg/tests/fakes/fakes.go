@@ -0,0 +1,158 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package fakes provides in-process fakes of all upstream services of the aggregator, serving seedable Fixtures.
+package fakes
+
+import (
+	"github.com/SENERGY-Platform/api-aggregator/pkg"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Upstreams runs one httptest.Server per upstream service
+type Upstreams struct {
+	mux      sync.RWMutex
+	fixtures Fixtures
+	failures map[string]int
+	pauses   map[string]*pause
+	requests map[string]int
+	servers  map[string]*httptest.Server
+}
+
+// New starts the fake upstreams; Close must be called to stop them
+func New(fixtures Fixtures) *Upstreams {
+	result := &Upstreams{
+		fixtures: fixtures,
+		failures: map[string]int{},
+		pauses:   map[string]*pause{},
+		requests: map[string]int{},
+		servers:  map[string]*httptest.Server{},
+	}
+	handlers := map[string]http.Handler{
+		pkg.UpstreamIot:               result.deviceRepository(),
+		pkg.UpstreamImportRepo:        result.importRepository(),
+		pkg.UpstreamConnectionLog:     result.connectionLog(),
+		pkg.UpstreamCamundaWrapper:    result.camundaWrapper(),
+		pkg.UpstreamProcessDeployment: result.processDeployment(),
+		pkg.UpstreamEventManager:      result.eventManager(),
+	}
+	for name, handler := range handlers {
+		result.servers[name] = httptest.NewServer(result.middleware(name, handler))
+	}
+	return result
+}
+
+// Config returns config with the urls of the fake upstreams
+func (this *Upstreams) Config(config pkg.Config) pkg.Config {
+	config.IotUrl = this.Url(pkg.UpstreamIot)
+	config.ImportRepoUrl = this.Url(pkg.UpstreamImportRepo)
+	config.ConnectionLogUrl = this.Url(pkg.UpstreamConnectionLog)
+	config.CamundaWrapperUrl = this.Url(pkg.UpstreamCamundaWrapper)
+	config.ProcessDeploymentUrl = this.Url(pkg.UpstreamProcessDeployment)
+	config.EventManagerUrl = this.Url(pkg.UpstreamEventManager)
+	return config
+}
+
+func (this *Upstreams) Url(upstream string) string {
+	return this.servers[upstream].URL
+}
+
+func (this *Upstreams) Close() {
+	for _, server := range this.servers {
+		server.Close()
+	}
+}
+
+// Update changes the served fixtures
+func (this *Upstreams) Update(f func(fixtures *Fixtures)) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	f(&this.fixtures)
+}
+
+// Fail lets all requests to upstream respond with statusCode; 0 restores normal operation
+func (this *Upstreams) Fail(upstream string, statusCode int) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.failures[upstream] = statusCode
+}
+
+type pause struct {
+	arrived  chan struct{}
+	released chan struct{}
+}
+
+// Pause holds all requests to upstream until release is called. like a slow upstream, the held responses contain
+// the fixtures at the time of the request. arrived receives a value for each held request, so tests can wait for in-flight requests.
+func (this *Upstreams) Pause(upstream string) (arrived <-chan struct{}, release func()) {
+	p := &pause{arrived: make(chan struct{}, 1000), released: make(chan struct{})}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.pauses[upstream] = p
+	once := sync.Once{}
+	return p.arrived, func() {
+		once.Do(func() {
+			this.mux.Lock()
+			defer this.mux.Unlock()
+			if this.pauses[upstream] == p {
+				delete(this.pauses, upstream)
+			}
+			close(p.released)
+		})
+	}
+}
+
+// Requests returns the count of requests received by upstream
+func (this *Upstreams) Requests(upstream string) int {
+	this.mux.RLock()
+	defer this.mux.RUnlock()
+	return this.requests[upstream]
+}
+
+func (this *Upstreams) middleware(upstream string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		this.mux.Lock()
+		this.requests[upstream]++
+		failure := this.failures[upstream]
+		paused := this.pauses[upstream]
+		this.mux.Unlock()
+		if failure != 0 {
+			http.Error(writer, "fake "+upstream+" failure", failure)
+			return
+		}
+		if paused == nil {
+			handler.ServeHTTP(writer, request)
+			return
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		paused.arrived <- struct{}{}
+		<-paused.released
+		maps.Copy(writer.Header(), recorder.Header())
+		writer.WriteHeader(recorder.Code)
+		writer.Write(recorder.Body.Bytes())
+	})
+}
+
+// read calls f with the current fixtures
+func read[T any](this *Upstreams, f func(fixtures *Fixtures) T) T {
+	this.mux.RLock()
+	defer this.mux.RUnlock()
+	return f(&this.fixtures)
+}
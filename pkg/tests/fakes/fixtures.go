@@ -0,0 +1,68 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/api-aggregator/pkg"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	importmodel "github.com/SENERGY-Platform/import-repository/lib/model"
+	"github.com/SENERGY-Platform/models/go/models"
+	"os"
+)
+
+// Fixtures is the state served by the fake upstreams
+type Fixtures struct {
+	//device-repository
+	Devices            []models.ExtendedDevice              `json:"devices"`
+	ForbiddenDevices   []models.ExtendedDevice              `json:"forbidden_devices"` //exist but are not readable by the user
+	Hubs               []models.ExtendedHub                 `json:"hubs"`
+	DeviceTypes        []models.DeviceType                  `json:"device_types"` //set as ExtendedDevice.DeviceType if fulldt=true is requested
+	Functions          []models.Function                    `json:"functions"`
+	DeviceClasses      []models.DeviceClass                 `json:"device_classes"`
+	AspectNodes        []model.AspectNode                   `json:"aspect_nodes"`
+	DeviceAspectNodes  []string                             `json:"device_aspect_nodes"` //ids of AspectNodes returned by /aspect-nodes?function=measuring-function
+	MeasuringFunctions map[string][]model.MeasuringFunction `json:"measuring_functions"` //by aspect id
+
+	//import-repository
+	ImportTypes []importmodel.ImportType `json:"import_types"`
+
+	//connection-log; ids missing in DeviceStates/HubStates are reported as offline
+	DeviceStates  map[string]bool                `json:"device_states"`
+	HubStates     map[string]bool                `json:"hub_states"`
+	DeviceHistory map[string]model.HistorySeries `json:"device_history"`
+	HubHistory    map[string]model.HistorySeries `json:"hub_history"`
+	DeviceEdges   map[string]interface{}         `json:"device_edges"`
+	HubEdges      map[string]interface{}         `json:"hub_edges"`
+	DeviceStarts  map[string]interface{}         `json:"device_starts"`
+	HubStarts     map[string]interface{}         `json:"hub_starts"`
+
+	//camunda-wrapper, process-deployment and event-manager; ids missing in EventStates are reported as offline
+	Deployments  []model.ProcessDeployment `json:"deployments"`
+	Dependencies []pkg.Dependencies        `json:"dependencies"`
+	EventStates  map[string]bool           `json:"event_states"`
+}
+
+// LoadFixtures reads Fixtures from a json file
+func LoadFixtures(location string) (result Fixtures, err error) {
+	file, err := os.ReadFile(location)
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(file, &result)
+	return result, err
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"encoding/json"
+	importmodel "github.com/SENERGY-Platform/import-repository/lib/model"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"slices"
+)
+
+func (this *Upstreams) importRepository() http.Handler {
+	router := httprouter.New()
+	router.GET("/import-types", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		importTypes := read(this, func(fixtures *Fixtures) []importmodel.ImportType { return slices.Clone(fixtures.ImportTypes) })
+		if criteria := request.URL.Query().Get("criteria"); criteria != "" {
+			filters := []importmodel.ImportTypeFilterCriteria{}
+			err := json.Unmarshal([]byte(criteria), &filters)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+			importTypes = filter(importTypes, func(importType importmodel.ImportType) bool {
+				return matchesCriteria(importType.Output, filters)
+			})
+		}
+		writeList(writer, request, importTypes, func(importType importmodel.ImportType) (string, string) { return importType.Id, importType.Name })
+	})
+	return router
+}
+
+func matchesCriteria(variable importmodel.ContentVariable, filters []importmodel.ImportTypeFilterCriteria) bool {
+	for _, f := range filters {
+		if (f.FunctionId == "" || f.FunctionId == variable.FunctionId) && (len(f.AspectIds) == 0 || slices.Contains(f.AspectIds, variable.AspectId)) && (variable.FunctionId != "" || variable.AspectId != "") {
+			return true
+		}
+	}
+	for _, sub := range variable.SubContentVariables {
+		if matchesCriteria(sub, filters) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"github.com/SENERGY-Platform/api-aggregator/pkg"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/model"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+func (this *Upstreams) camundaWrapper() http.Handler {
+	router := httprouter.New()
+	router.GET("/deployment", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		writeJson(writer, read(this, func(fixtures *Fixtures) []model.ProcessDeployment {
+			return filter(fixtures.Deployments, func(deployment model.ProcessDeployment) bool {
+				return strings.Contains(deployment.Name, request.URL.Query().Get("nameLike"))
+			})
+		}))
+	})
+	return router
+}
+
+func (this *Upstreams) processDeployment() http.Handler {
+	router := httprouter.New()
+	router.GET("/dependencies", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		ids := strings.Split(request.URL.Query().Get("ids"), ",")
+		writeJson(writer, read(this, func(fixtures *Fixtures) []pkg.Dependencies {
+			return filter(fixtures.Dependencies, func(dependency pkg.Dependencies) bool { return slices.Contains(ids, dependency.DeploymentId) })
+		}))
+	})
+	return router
+}
+
+func (this *Upstreams) eventManager() http.Handler {
+	router := httprouter.New()
+	router.GET("/event-states", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		ids := strings.Split(request.URL.Query().Get("ids"), ",")
+		writeJson(writer, read(this, func(fixtures *Fixtures) map[string]bool {
+			result := map[string]bool{}
+			for _, id := range ids {
+				if id != "" {
+					result[id] = fixtures.EventStates[id]
+				}
+			}
+			return result
+		}))
+	})
+	return router
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"github.com/SENERGY-Platform/api-aggregator/pkg"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/api"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/tests/fakes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestRecordAndReplay records the upstream exchanges of some requests against the fake upstreams
+// and expects identical responses when replaying the recording without any upstream
+func TestRecordAndReplay(t *testing.T) {
+	paths := []string{"/v2/devices?log=1h", "/v2/hubs", "/processes", "/v2/processes", "/aspect-nodes", "/aspects/urn:ses:aspect:air/measuring-functions",
+		"/device-class-uses", "/v2/devices?attribute=room:kitchen&attribute=floor:1&attribute=building:a"}
+	location := filepath.Join(t.TempDir(), "recording.jsonl")
+
+	fixtures, err := fakes.LoadFixtures("testdata/fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstreams := fakes.New(fixtures)
+	config, middleware, shutdown, err := upstreams.Config(pkg.Config{RecordUpstreams: location}).UpstreamMiddleware()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := requestAll(t, api.NewHandler(pkg.NewWithMiddleware(config, middleware)), paths)
+	upstreams.Close()
+	err = shutdown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, middleware, _, err = pkg.Config{ReplayUpstreams: location}.UpstreamMiddleware()
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := requestAll(t, api.NewHandler(pkg.NewWithMiddleware(config, middleware)), paths)
+	if !reflect.DeepEqual(recorded, replayed) {
+		t.Errorf("\n%#v\n%#v", recorded, replayed)
+	}
+
+	replayed = requestAll(t, api.NewHandler(pkg.NewWithMiddleware(config, middleware)), []string{"/v2/devices?limit=1"})
+	if !strings.Contains(replayed[0], "no recorded exchange for iot GET /extended-devices") {
+		t.Error(replayed[0])
+	}
+}
+
+func requestAll(t *testing.T, handler http.Handler, paths []string) (result []string) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	for _, path := range paths {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", testjwt)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		result = append(result, resp.Status+" "+resp.Header.Get(api.WarningsHeader)+" "+string(body))
+	}
+	return result
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/api-aggregator/pkg"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/api"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/client"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/tests/fakes"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// exportedSpan is the part of the spans written by the stdout and file exporters that is checked by the tests
+type exportedSpan struct {
+	Name        string
+	SpanKind    trace.SpanKind
+	SpanContext struct{ TraceID, SpanID string }
+	Parent      struct{ TraceID, SpanID string }
+}
+
+func TestTracingFileExporter(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "traces.json")
+	requestTraced(t, pkg.Config{TracingExporter: pkg.TracingExporterFile, TracingFile: location})
+
+	file, err := os.Open(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	spans := []exportedSpan{}
+	decoder := json.NewDecoder(file)
+	for {
+		span := exportedSpan{}
+		err = decoder.Decode(&span)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		spans = append(spans, span)
+	}
+
+	var server, upstream *exportedSpan
+	for i, span := range spans {
+		switch span.SpanKind {
+		case trace.SpanKindServer:
+			server = &spans[i]
+		case trace.SpanKindClient:
+			upstream = &spans[i]
+		}
+	}
+	if server == nil || upstream == nil || server.Name != "GET /aspects/:id/measuring-functions" ||
+		upstream.Parent.SpanID != server.SpanContext.SpanID || upstream.SpanContext.TraceID != server.SpanContext.TraceID {
+		t.Errorf("%#v", spans)
+	}
+}
+
+func TestTracingOtlpExporter(t *testing.T) {
+	exports := atomic.Int64{}
+	collector := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method == http.MethodPost && request.URL.Path == "/v1/traces" {
+			exports.Add(1)
+		}
+	}))
+	defer collector.Close()
+	requestTraced(t, pkg.Config{TracingExporter: pkg.TracingExporterOtlp, TracingOtlpEndpoint: collector.URL + "/v1/traces"})
+	if exports.Load() == 0 {
+		t.Error("no spans exported to the otlp endpoint")
+	}
+}
+
+func TestTracingStdoutExporter(t *testing.T) {
+	requestTraced(t, pkg.Config{TracingExporter: pkg.TracingExporterStdout})
+}
+
+func TestTracingUnknownExporter(t *testing.T) {
+	_, err := pkg.SetupTracing(context.Background(), pkg.Config{TracingExporter: "unknown"})
+	if err == nil {
+		t.Error("expected error")
+	}
+}
+
+// requestTraced sets up tracing with config, requests the measuring functions of an aspect (one device-repository request)
+// and flushes the spans by the shutdown function of pkg.SetupTracing
+func requestTraced(t *testing.T, config pkg.Config) {
+	t.Helper()
+	shutdown, err := pkg.SetupTracing(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	fixtures, err := fakes.LoadFixtures("testdata/fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstreams := fakes.New(fixtures)
+	defer upstreams.Close()
+	server := httptest.NewServer(api.NewHandler(pkg.New(upstreams.Config(pkg.Config{}))))
+	_, err, _ = client.NewClient(server.URL, nil).GetMeasuringFunctionsForAspect(context.Background(), testjwt, "urn:ses:aspect:air")
+	server.Close() //waits for the end of the server span
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = shutdown(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"os"
+)
+
+const (
+	TracingExporterNone   = ""
+	TracingExporterStdout = "stdout"
+	TracingExporterFile   = "file"
+	TracingExporterOtlp   = "otlp"
+)
+
+// SetupTracing installs the global W3C trace context propagator and, if config.TracingExporter is set, a tracer provider
+// exporting to stdout, to config.TracingFile or to the otlp/http endpoint config.TracingOtlpEndpoint.
+// the returned shutdown function flushes pending spans.
+func SetupTracing(ctx context.Context, config Config) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	shutdown = func(context.Context) error { return nil }
+
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+	switch config.TracingExporter {
+	case TracingExporterNone, "-":
+		return shutdown, nil
+	case TracingExporterStdout:
+		exporter, err = stdouttrace.New()
+	case TracingExporterFile:
+		file, err = os.OpenFile(config.TracingFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return shutdown, err
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	case TracingExporterOtlp:
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(config.TracingOtlpEndpoint))
+	default:
+		err = errors.New("unknown tracing_exporter " + config.TracingExporter)
+	}
+	if err != nil {
+		return shutdown, err
+	}
+
+	serviceName := config.TracingServiceName
+	if serviceName == "" {
+		serviceName = "api-aggregator"
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			err = errors.Join(err, file.Close())
+		}
+		return err
+	}, nil
+}
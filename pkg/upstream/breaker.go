@@ -0,0 +1,196 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package upstream
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+type BreakerState = string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	outcomeIgnored
+)
+
+// CircuitBreaker opens after FailureThreshold consecutive failures and lets requests fail fast until OpenTimeout elapsed.
+// after that a single trial request decides if the breaker closes again.
+type CircuitBreaker struct {
+	url              string
+	failureThreshold int
+	openTimeout      time.Duration
+
+	mux          sync.Mutex
+	upstreams    []string
+	state        BreakerState
+	failures     int
+	openedAt     time.Time
+	trialRunning bool
+	now          func() time.Time
+}
+
+type CircuitBreakerStatus struct {
+	Url                 string       `json:"url"`
+	Upstreams           []string     `json:"upstreams"`
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	OpenedAt            *time.Time   `json:"opened_at,omitempty"`
+}
+
+func newCircuitBreaker(url string, failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		url:              url,
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		state:            BreakerClosed,
+		now:              time.Now,
+	}
+}
+
+// allow returns ErrCircuitOpen if the request may not be sent.
+// otherwise the returned function must be called with the outcome of the request.
+func (this *CircuitBreaker) allow() (report func(outcome), err error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	trial := false
+	switch this.state {
+	case BreakerOpen:
+		if this.now().Sub(this.openedAt) < this.openTimeout {
+			return nil, ErrCircuitOpen
+		}
+		this.state = BreakerHalfOpen
+		this.trialRunning = true
+		trial = true
+	case BreakerHalfOpen:
+		if this.trialRunning {
+			return nil, ErrCircuitOpen
+		}
+		this.trialRunning = true
+		trial = true
+	}
+	return func(o outcome) {
+		this.report(trial, o)
+	}, nil
+}
+
+func (this *CircuitBreaker) report(trial bool, o outcome) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if trial {
+		this.trialRunning = false
+	} else if this.state != BreakerClosed {
+		//late result of a request started before the breaker opened
+		return
+	}
+	switch o {
+	case outcomeSuccess:
+		this.failures = 0
+		this.state = BreakerClosed
+	case outcomeFailure:
+		this.failures++
+		if trial || this.failures >= this.failureThreshold {
+			this.state = BreakerOpen
+			this.openedAt = this.now()
+		}
+	}
+}
+
+func (this *CircuitBreaker) Status() CircuitBreakerStatus {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	result := CircuitBreakerStatus{
+		Url:                 this.url,
+		Upstreams:           slices.Clone(this.upstreams),
+		State:               this.state,
+		ConsecutiveFailures: this.failures,
+	}
+	if this.state != BreakerClosed {
+		openedAt := this.openedAt
+		result.OpenedAt = &openedAt
+	}
+	return result
+}
+
+// CircuitBreakers holds one CircuitBreaker per upstream base url.
+// upstreams sharing a base url share their breaker.
+type CircuitBreakers struct {
+	failureThreshold int
+	openTimeout      time.Duration
+	mux              sync.Mutex
+	breakers         map[string]*CircuitBreaker
+}
+
+// NewCircuitBreakers creates a breaker registry. a failureThreshold <= 0 disables circuit breaking.
+func NewCircuitBreakers(failureThreshold int, openTimeout time.Duration) *CircuitBreakers {
+	return &CircuitBreakers{
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		breakers:         map[string]*CircuitBreaker{},
+	}
+}
+
+// Get returns the breaker for baseUrl and registers upstream as user of it.
+// returns nil if circuit breaking is disabled.
+func (this *CircuitBreakers) Get(upstream string, baseUrl string) *CircuitBreaker {
+	if this == nil || this.failureThreshold <= 0 || baseUrl == "" || baseUrl == "-" {
+		return nil
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	breaker, ok := this.breakers[baseUrl]
+	if !ok {
+		breaker = newCircuitBreaker(baseUrl, this.failureThreshold, this.openTimeout)
+		this.breakers[baseUrl] = breaker
+	}
+	breaker.mux.Lock()
+	if !slices.Contains(breaker.upstreams, upstream) {
+		breaker.upstreams = append(breaker.upstreams, upstream)
+	}
+	breaker.mux.Unlock()
+	return breaker
+}
+
+func (this *CircuitBreakers) Status() (result []CircuitBreakerStatus) {
+	result = []CircuitBreakerStatus{}
+	if this == nil {
+		return result
+	}
+	this.mux.Lock()
+	for _, breaker := range this.breakers {
+		result = append(result, breaker.Status())
+	}
+	this.mux.Unlock()
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Url < result[j].Url
+	})
+	return result
+}
@@ -0,0 +1,258 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SENERGY-Platform/api-aggregator/pkg/logging"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Client sends requests to a single upstream service.
+// Every upstream gets its own Client, so timeouts can be configured per upstream
+// and the http.DefaultClient stays untouched.
+type Client struct {
+	name     string
+	baseUrl  string
+	http     *http.Client
+	retry    RetryPolicy
+	breaker  *CircuitBreaker
+	observer Observer
+}
+
+type Options struct {
+	Timeout    time.Duration //0 means no timeout
+	Retry      RetryPolicy
+	Breakers   *CircuitBreakers //may be nil
+	Observer   Observer         //may be nil
+	Middleware Middleware       //may be nil; e.g. Recorder.Middleware or Replayer.Middleware
+}
+
+// New creates a Client for the upstream reachable at baseUrl.
+func New(name string, baseUrl string, options Options) *Client {
+	var transport http.RoundTripper = http.DefaultTransport
+	if options.Middleware != nil {
+		transport = options.Middleware(name, transport)
+	}
+	return &Client{
+		name:     name,
+		baseUrl:  baseUrl,
+		http:     &http.Client{Timeout: options.Timeout, Transport: newTracingTransport(name, transport)},
+		retry:    options.Retry,
+		breaker:  options.Breakers.Get(name, baseUrl),
+		observer: options.Observer,
+	}
+}
+
+func (this *Client) Name() string {
+	return this.name
+}
+
+func (this *Client) BaseUrl() string {
+	return this.baseUrl
+}
+
+// Enabled returns false if no url is configured for the upstream ("" or "-")
+func (this *Client) Enabled() bool {
+	return this.baseUrl != "" && this.baseUrl != "-"
+}
+
+// ResponseError is returned if the upstream responds with a status code >= 300
+type ResponseError struct {
+	Upstream   string
+	StatusCode int
+	Message    string
+}
+
+func (this *ResponseError) Error() string {
+	return fmt.Sprintf("%v: unexpected statuscode %v: %v", this.Upstream, this.StatusCode, this.Message)
+}
+
+// NewRequest creates a request to the upstream. the request id of ctx is forwarded as X-Request-Id header.
+func (this *Client) NewRequest(ctx context.Context, method string, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, this.baseUrl+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if id := logging.RequestId(ctx); id != "" {
+		req.Header.Set(logging.RequestIdHeader, id)
+	}
+	return req, nil
+}
+
+// Do sends req. GET and HEAD requests are retried according to the RetryPolicy of the Client.
+func (this *Client) Do(req *http.Request) (*http.Response, error) {
+	return this.do(req, isIdempotent(req.Method))
+}
+
+func (this *Client) do(req *http.Request, retryable bool) (resp *http.Response, err error) {
+	attempts := 1
+	if retryable && this.retry.MaxAttempts > 1 {
+		attempts = this.retry.MaxAttempts
+	}
+	for attempt := 1; ; attempt++ {
+		resp, err = this.send(req)
+		if attempt >= attempts || !isRetryable(req.Context(), resp, err) {
+			return resp, err
+		}
+		this.logger(req).InfoContext(req.Context(), "retry upstream request", "attempt", attempt, "status", status(resp, err))
+		discard(resp)
+		err = this.retry.wait(req.Context(), attempt)
+		if err != nil {
+			return nil, err
+		}
+		req, err = rewind(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (this *Client) send(req *http.Request) (resp *http.Response, err error) {
+	start := time.Now()
+	report := func(outcome) {}
+	if this.breaker != nil {
+		report, err = this.breaker.allow()
+		if err != nil {
+			this.observe(req, StatusCircuitOpen, true, start)
+			return nil, fmt.Errorf("%v: %w", this.name, err)
+		}
+	}
+	resp, err = this.http.Do(req)
+	switch {
+	case req.Context().Err() != nil:
+		report(outcomeIgnored)
+		this.observe(req, StatusError, false, start)
+	case err != nil:
+		report(outcomeFailure)
+		this.observe(req, StatusError, true, start)
+	case resp.StatusCode >= 500:
+		report(outcomeFailure)
+		this.observe(req, strconv.Itoa(resp.StatusCode), true, start)
+	default:
+		report(outcomeSuccess)
+		this.observe(req, strconv.Itoa(resp.StatusCode), false, start)
+	}
+	return resp, err
+}
+
+func (this *Client) observe(req *http.Request, status string, failed bool, start time.Time) {
+	duration := time.Since(start)
+	level := slog.LevelDebug
+	if failed {
+		level = slog.LevelWarn
+	}
+	this.logger(req).Log(req.Context(), level, "upstream request", "status", status, "duration", duration)
+	if this.observer != nil {
+		this.observer.ObserveUpstreamRequest(this.name, req.Method, Endpoint(req.URL.Path), status, failed, duration)
+	}
+}
+
+func (this *Client) logger(req *http.Request) *slog.Logger {
+	return slog.With("upstream", this.name, "method", req.Method, "endpoint", Endpoint(req.URL.Path))
+}
+
+func status(resp *http.Response, err error) string {
+	if err != nil || resp == nil {
+		return StatusError
+	}
+	return strconv.Itoa(resp.StatusCode)
+}
+
+func (this *Client) Get(ctx context.Context, token string, path string) (resp *http.Response, err error) {
+	req, err := this.NewRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	return this.Do(req)
+}
+
+func (this *Client) Post(ctx context.Context, token string, path string, contentType string, body io.Reader) (resp *http.Response, err error) {
+	return this.post(ctx, token, path, contentType, body, false)
+}
+
+func (this *Client) post(ctx context.Context, token string, path string, contentType string, body io.Reader, readOnly bool) (resp *http.Response, err error) {
+	req, err := this.NewRequest(ctx, http.MethodPost, path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	return this.do(req, readOnly)
+}
+
+func (this *Client) GetJson(ctx context.Context, token string, path string, out interface{}) (err error) {
+	resp, err := this.Get(ctx, token, path)
+	if err != nil {
+		return err
+	}
+	return this.decodeJson(resp, out)
+}
+
+func (this *Client) PostJson(ctx context.Context, token string, path string, in interface{}, out interface{}) (err error) {
+	return this.postJson(ctx, token, path, in, out, false)
+}
+
+// QueryJson is like PostJson but for read-only POST endpoints (e.g. /query/aspect-nodes).
+// because these requests have no side effects, they are retried like GET requests.
+func (this *Client) QueryJson(ctx context.Context, token string, path string, in interface{}, out interface{}) (err error) {
+	return this.postJson(ctx, token, path, in, out, true)
+}
+
+func (this *Client) postJson(ctx context.Context, token string, path string, in interface{}, out interface{}, readOnly bool) (err error) {
+	requestBody := new(bytes.Buffer)
+	err = json.NewEncoder(requestBody).Encode(in)
+	if err != nil {
+		return err
+	}
+	resp, err := this.post(ctx, token, path, "application/json", requestBody, readOnly)
+	if err != nil {
+		return err
+	}
+	return this.decodeJson(resp, out)
+}
+
+// CheckResponse returns a *ResponseError if resp has a status code >= 300.
+// the response body is consumed in this case.
+func (this *Client) CheckResponse(resp *http.Response) error {
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	responseMsg, _ := io.ReadAll(resp.Body)
+	return &ResponseError{Upstream: this.name, StatusCode: resp.StatusCode, Message: string(responseMsg)}
+}
+
+func (this *Client) decodeJson(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	err := this.CheckResponse(resp)
+	if err != nil {
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
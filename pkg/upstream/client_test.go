@@ -0,0 +1,169 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package upstream
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	calls := atomic.Int64{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if calls.Add(1) < 3 {
+			http.Error(writer, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		writer.Write([]byte(`{"foo":"bar"}`))
+	}))
+	defer server.Close()
+
+	client := New("test", server.URL, Options{Retry: RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}})
+
+	t.Run("get is retried", func(t *testing.T) {
+		result := map[string]string{}
+		err := client.GetJson(context.Background(), "", "/", &result)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if result["foo"] != "bar" || calls.Load() != 3 {
+			t.Error(result, calls.Load())
+		}
+	})
+
+	t.Run("query is retried", func(t *testing.T) {
+		calls.Store(0)
+		result := map[string]string{}
+		err := client.QueryJson(context.Background(), "", "/", []string{"a"}, &result)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if result["foo"] != "bar" || calls.Load() != 3 {
+			t.Error(result, calls.Load())
+		}
+	})
+
+	t.Run("post is not retried", func(t *testing.T) {
+		calls.Store(0)
+		result := map[string]string{}
+		err := client.PostJson(context.Background(), "", "/", []string{"a"}, &result)
+		var respErr *ResponseError
+		if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusServiceUnavailable {
+			t.Error(err)
+		}
+		if calls.Load() != 1 {
+			t.Error(calls.Load())
+		}
+	})
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	healthy := atomic.Bool{}
+	calls := atomic.Int64{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			http.Error(writer, "error", http.StatusInternalServerError)
+			return
+		}
+		writer.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	breakers := NewCircuitBreakers(2, time.Hour)
+	client := New("test", server.URL, Options{Breakers: breakers})
+	now := time.Now()
+	breaker := breakers.Get("test", server.URL)
+	breaker.now = func() time.Time {
+		return now
+	}
+
+	result := map[string]interface{}{}
+	for i := 0; i < 2; i++ {
+		err := client.GetJson(context.Background(), "", "/", &result)
+		if err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Error(err)
+		}
+	}
+	err := client.GetJson(context.Background(), "", "/", &result)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Error(err)
+	}
+	if calls.Load() != 2 {
+		t.Error(calls.Load())
+	}
+	status := breakers.Status()
+	if len(status) != 1 || status[0].State != BreakerOpen || status[0].ConsecutiveFailures != 2 {
+		t.Errorf("%#v", status)
+	}
+
+	healthy.Store(true)
+	now = now.Add(2 * time.Hour)
+	err = client.GetJson(context.Background(), "", "/", &result)
+	if err != nil {
+		t.Error(err)
+	}
+	status = breakers.Status()
+	if len(status) != 1 || status[0].State != BreakerClosed || status[0].ConsecutiveFailures != 0 {
+		t.Errorf("%#v", status)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	traceparent := ""
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		traceparent = request.Header.Get("traceparent")
+		writer.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "handler")
+	result := map[string]interface{}{}
+	err := New("test", server.URL, Options{}).GetJson(ctx, "", "/devices/urn:infai:ses:device:1", &result)
+	span.End()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !strings.Contains(traceparent, span.SpanContext().TraceID().String()) {
+		t.Error("missing trace id in traceparent header:", traceparent)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 || spans[0].Name != "test GET /devices/:param" || spans[0].Parent.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("%#v", spans.Snapshots())
+	}
+}
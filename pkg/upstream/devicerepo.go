@@ -0,0 +1,235 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/models/go/models"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DeviceRepository is the context aware subset of the device-repository api used by the aggregator.
+// the query encoding mirrors github.com/SENERGY-Platform/device-repository/lib/client
+type DeviceRepository interface {
+	ListExtendedDevices(ctx context.Context, token string, options client.ExtendedDeviceListOptions) (result []models.ExtendedDevice, total int64, err error, errCode int)
+	ReadExtendedDevice(ctx context.Context, token string, id string, permission models.PermissionFlag, fullDt bool) (result models.ExtendedDevice, err error, errCode int)
+	ReadExtendedHub(ctx context.Context, token string, id string, permission models.PermissionFlag) (result models.ExtendedHub, err error, errCode int)
+	ListExtendedHubs(ctx context.Context, token string, options client.HubListOptions) (result []models.ExtendedHub, total int64, err error, errCode int)
+	ListFunctions(ctx context.Context, options client.FunctionListOptions) (result []models.Function, total int64, err error, errCode int)
+	ListDeviceClasses(ctx context.Context, options client.DeviceClassListOptions) (result []models.DeviceClass, total int64, err error, errCode int)
+}
+
+type DeviceRepositoryClient struct {
+	client *Client
+}
+
+func NewDeviceRepository(client *Client) DeviceRepository {
+	return &DeviceRepositoryClient{client: client}
+}
+
+func (this *DeviceRepositoryClient) ListExtendedDevices(ctx context.Context, token string, options client.ExtendedDeviceListOptions) (result []models.ExtendedDevice, total int64, err error, errCode int) {
+	query := url.Values{}
+	if options.Permission != models.UnsetPermissionFlag {
+		query.Set("p", string(options.Permission))
+	}
+	if options.Search != "" {
+		query.Set("search", options.Search)
+	}
+	if options.Ids != nil {
+		query.Set("ids", strings.Join(options.Ids, ","))
+	}
+	if options.LocalIds != nil {
+		query.Set("local_ids", strings.Join(options.LocalIds, ","))
+	}
+	if options.Owner != "" {
+		query.Set("owner", options.Owner)
+	}
+	if options.DeviceTypeIds != nil {
+		query.Set("device-type-ids", strings.Join(options.DeviceTypeIds, ","))
+	}
+	if options.ConnectionState != nil {
+		query.Set("connection-state", *options.ConnectionState)
+	}
+	if options.SortBy != "" {
+		query.Set("sort", options.SortBy)
+	}
+	if options.Limit != 0 {
+		query.Set("limit", strconv.FormatInt(options.Limit, 10))
+	}
+	if options.Offset != 0 {
+		query.Set("offset", strconv.FormatInt(options.Offset, 10))
+	}
+	if options.AttributeKeys != nil {
+		query.Set("attr-keys", strings.Join(options.AttributeKeys, ","))
+	}
+	if options.AttributeValues != nil {
+		query.Set("attr-values", strings.Join(options.AttributeValues, ","))
+	}
+	if options.FullDt {
+		query.Set("fulldt", "true")
+	}
+	return listWithTotal[[]models.ExtendedDevice](ctx, this.client, token, "/extended-devices", query)
+}
+
+func (this *DeviceRepositoryClient) ReadExtendedDevice(ctx context.Context, token string, id string, permission models.PermissionFlag, fullDt bool) (result models.ExtendedDevice, err error, errCode int) {
+	query := url.Values{}
+	if permission != models.UnsetPermissionFlag {
+		query.Set("p", string(permission))
+	}
+	if fullDt {
+		query.Set("fulldt", "true")
+	}
+	return read[models.ExtendedDevice](ctx, this.client, token, "/extended-devices/"+url.PathEscape(id), query)
+}
+
+func (this *DeviceRepositoryClient) ReadExtendedHub(ctx context.Context, token string, id string, permission models.PermissionFlag) (result models.ExtendedHub, err error, errCode int) {
+	query := url.Values{}
+	if permission != models.UnsetPermissionFlag {
+		query.Set("p", string(permission))
+	}
+	return read[models.ExtendedHub](ctx, this.client, token, "/extended-hubs/"+url.PathEscape(id), query)
+}
+
+func (this *DeviceRepositoryClient) ListExtendedHubs(ctx context.Context, token string, options client.HubListOptions) (result []models.ExtendedHub, total int64, err error, errCode int) {
+	query := url.Values{}
+	if options.Permission != models.UnsetPermissionFlag {
+		query.Set("p", string(options.Permission))
+	}
+	if options.Search != "" {
+		query.Set("search", options.Search)
+	}
+	if options.Ids != nil {
+		query.Set("ids", strings.Join(options.Ids, ","))
+	}
+	if options.ConnectionState != nil {
+		query.Set("connection-state", *options.ConnectionState)
+	}
+	if options.SortBy != "" {
+		query.Set("sort", options.SortBy)
+	}
+	if options.Limit != 0 {
+		query.Set("limit", strconv.FormatInt(options.Limit, 10))
+	}
+	if options.Offset != 0 {
+		query.Set("offset", strconv.FormatInt(options.Offset, 10))
+	}
+	if options.LocalDeviceId != "" {
+		query.Set("local-device-id", options.LocalDeviceId)
+	}
+	if options.OwnerId != "" {
+		query.Set("owner", options.OwnerId)
+	}
+	return listWithTotal[[]models.ExtendedHub](ctx, this.client, token, "/extended-hubs", query)
+}
+
+func (this *DeviceRepositoryClient) ListFunctions(ctx context.Context, options client.FunctionListOptions) (result []models.Function, total int64, err error, errCode int) {
+	query := url.Values{}
+	if options.Search != "" {
+		query.Set("search", options.Search)
+	}
+	if options.RdfType != "" {
+		query.Set("rdf_type", options.RdfType)
+	}
+	if options.Ids != nil {
+		query.Set("ids", strings.Join(options.Ids, ","))
+	}
+	if options.SortBy != "" {
+		query.Set("sort", options.SortBy)
+	}
+	if options.Limit != 0 {
+		query.Set("limit", strconv.FormatInt(options.Limit, 10))
+	}
+	if options.Offset != 0 {
+		query.Set("offset", strconv.FormatInt(options.Offset, 10))
+	}
+	return listWithTotal[[]models.Function](ctx, this.client, "", "/functions", query)
+}
+
+func (this *DeviceRepositoryClient) ListDeviceClasses(ctx context.Context, options client.DeviceClassListOptions) (result []models.DeviceClass, total int64, err error, errCode int) {
+	query := url.Values{}
+	if options.Search != "" {
+		query.Set("search", options.Search)
+	}
+	if options.Ids != nil {
+		query.Set("ids", strings.Join(options.Ids, ","))
+	}
+	if options.SortBy != "" {
+		query.Set("sort", options.SortBy)
+	}
+	if options.Limit != 0 {
+		query.Set("limit", strconv.FormatInt(options.Limit, 10))
+	}
+	if options.Offset != 0 {
+		query.Set("offset", strconv.FormatInt(options.Offset, 10))
+	}
+	return listWithTotal[[]models.DeviceClass](ctx, this.client, "", "/v2/device-classes", query)
+}
+
+// listWithTotal requests a list endpoint that returns the total count in the X-Total-Count header
+func listWithTotal[T any](ctx context.Context, c *Client, token string, path string, query url.Values) (result T, total int64, err error, code int) {
+	if len(query) > 0 {
+		path = path + "?" + query.Encode()
+	}
+	resp, err := c.Get(ctx, token, path)
+	if err != nil {
+		return result, total, err, http.StatusInternalServerError
+	}
+	defer resp.Body.Close()
+	err = c.CheckResponse(resp)
+	if err != nil {
+		return result, total, err, resp.StatusCode
+	}
+	total, err = strconv.ParseInt(resp.Header.Get("X-Total-Count"), 10, 64)
+	if err != nil {
+		return result, total, fmt.Errorf("unable to read X-Total-Count header %w", err), http.StatusInternalServerError
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		_, _ = io.ReadAll(resp.Body) //ensure resp.Body is read to EOF
+		return result, total, err, http.StatusInternalServerError
+	}
+	return result, total, nil, http.StatusOK
+}
+
+// read requests a single element; code is the status code of the upstream if it responds with an error
+func read[T any](ctx context.Context, c *Client, token string, path string, query url.Values) (result T, err error, code int) {
+	if len(query) > 0 {
+		path = path + "?" + query.Encode()
+	}
+	resp, err := c.Get(ctx, token, path)
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	defer resp.Body.Close()
+	err = c.CheckResponse(resp)
+	if err != nil {
+		return result, err, resp.StatusCode
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		_, _ = io.ReadAll(resp.Body) //ensure resp.Body is read to EOF
+		return result, err, http.StatusInternalServerError
+	}
+	return result, nil, http.StatusOK
+}
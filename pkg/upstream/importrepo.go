@@ -0,0 +1,69 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/import-repository/lib/model"
+	"github.com/SENERGY-Platform/service-commons/pkg/jwt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ImportRepository is the context aware subset of the import-repository api used by the aggregator.
+// the query encoding mirrors github.com/SENERGY-Platform/import-repository/lib/client
+type ImportRepository interface {
+	ListImportTypes(ctx context.Context, token jwt.Token, options model.ImportTypeListOptions) (result []model.ImportType, total int64, err error, errCode int)
+}
+
+type ImportRepositoryClient struct {
+	client *Client
+}
+
+func NewImportRepository(client *Client) ImportRepository {
+	return &ImportRepositoryClient{client: client}
+}
+
+func (this *ImportRepositoryClient) ListImportTypes(ctx context.Context, token jwt.Token, options model.ImportTypeListOptions) (result []model.ImportType, total int64, err error, errCode int) {
+	query := url.Values{}
+	if options.Search != "" {
+		query.Set("search", options.Search)
+	}
+	if options.Ids != nil {
+		query.Set("ids", strings.Join(options.Ids, ","))
+	}
+	if options.SortBy != "" {
+		query.Set("sort", options.SortBy)
+	}
+	if options.Limit != 0 {
+		query.Set("limit", strconv.FormatInt(options.Limit, 10))
+	}
+	if options.Offset != 0 {
+		query.Set("offset", strconv.FormatInt(options.Offset, 10))
+	}
+	if len(options.Criteria) > 0 {
+		filterStr, err := json.Marshal(options.Criteria)
+		if err != nil {
+			return result, total, err, http.StatusBadRequest
+		}
+		query.Add("criteria", string(filterStr))
+	}
+	return listWithTotal[[]model.ImportType](ctx, this.client, token.Jwt(), "/import-types", query)
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package upstream
+
+import (
+	"strings"
+	"time"
+)
+
+// Observer is notified about every request attempt sent to an upstream (e.g. to collect metrics).
+// status is the response status code, "error" for transport errors or "circuit_open" if the circuit breaker rejected the request.
+type Observer interface {
+	ObserveUpstreamRequest(upstream string, method string, endpoint string, status string, failed bool, duration time.Duration)
+}
+
+const (
+	StatusError       = "error"
+	StatusCircuitOpen = "circuit_open"
+)
+
+// Endpoint returns a low cardinality representation of path, usable as metrics label or span name.
+// the query is removed and path segments containing ids (urns) or durations are replaced by ":param"
+func Endpoint(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.Contains(segment, ":") || strings.Contains(segment, "%3A") || (segment != "" && segment[0] >= '0' && segment[0] <= '9') {
+			segments[i] = ":param"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package upstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Middleware wraps the transport used for requests to upstream
+type Middleware func(upstream string, next http.RoundTripper) http.RoundTripper
+
+// Exchange is a recorded request to an upstream and its response.
+// the Authorization header is never recorded.
+type Exchange struct {
+	Upstream       string      `json:"upstream"`
+	Method         string      `json:"method"`
+	Path           string      `json:"path"` //including the query
+	RequestBody    string      `json:"request_body,omitempty"`
+	StatusCode     int         `json:"status_code"`
+	ResponseHeader http.Header `json:"response_header,omitempty"`
+	ResponseBody   string      `json:"response_body"`
+}
+
+func (this Exchange) key() string {
+	return exchangeKey(this.Upstream, this.Method, this.Path, this.RequestBody)
+}
+
+// exchangeKey matches requests literally, so upstream requests have to be deterministic to be replayed,
+// e.g. ids and filters built from maps have to be sorted.
+func exchangeKey(upstream string, method string, path string, body string) string {
+	return upstream + " " + method + " " + path + " " + body
+}
+
+// Recording is stored as JSON Lines file with one Exchange per line
+type Recording struct {
+	Exchanges []Exchange
+}
+
+func LoadRecording(location string) (result Recording, err error) {
+	file, err := os.Open(location)
+	if err != nil {
+		return result, err
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	for {
+		exchange := Exchange{}
+		err = decoder.Decode(&exchange)
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return result, fmt.Errorf("invalid recording %v: %w", location, err)
+		}
+		result.Exchanges = append(result.Exchanges, exchange)
+	}
+}
+
+// Recorder captures all upstream exchanges and appends them to a Recording file as soon as they are completed
+type Recorder struct {
+	mux  sync.Mutex
+	file *os.File
+}
+
+// NewRecorder creates the Recording file at location; an existing file is overwritten
+func NewRecorder(location string) (*Recorder, error) {
+	file, err := os.Create(location)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{file: file}, nil
+}
+
+func (this *Recorder) Middleware(upstream string, next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requestBody, err := peekRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			return resp, err
+		}
+		responseBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+		header := resp.Header.Clone()
+		header.Del("Date")
+		err = this.add(Exchange{
+			Upstream:       upstream,
+			Method:         req.Method,
+			Path:           req.URL.RequestURI(),
+			RequestBody:    requestBody,
+			StatusCode:     resp.StatusCode,
+			ResponseHeader: header,
+			ResponseBody:   string(responseBody),
+		})
+		return resp, err
+	})
+}
+
+// Close flushes and closes the Recording file; exchanges completed afterwards fail with os.ErrClosed
+func (this *Recorder) Close() error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return errors.Join(this.file.Sync(), this.file.Close())
+}
+
+// add appends exchange as one line; only the write is serialized
+func (this *Recorder) add(exchange Exchange) error {
+	line, err := json.Marshal(exchange)
+	if err != nil {
+		return err
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	_, err = this.file.Write(append(line, '\n'))
+	return err
+}
+
+// Replayer answers upstream requests from a Recording without network access.
+// exchanges are matched by upstream, method, path (including the query) and request body; the host is ignored.
+// repeated requests receive the recorded responses in order, the last one is repeated once all are used.
+// requests without recorded exchange receive a 501 Not Implemented response.
+type Replayer struct {
+	mux       sync.Mutex
+	exchanges map[string][]Exchange
+}
+
+func NewReplayer(recording Recording) *Replayer {
+	result := &Replayer{exchanges: map[string][]Exchange{}}
+	for _, exchange := range recording.Exchanges {
+		result.exchanges[exchange.key()] = append(result.exchanges[exchange.key()], exchange)
+	}
+	return result
+}
+
+func (this *Replayer) Middleware(upstream string, _ http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requestBody, err := peekRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		exchange, ok := this.next(exchangeKey(upstream, req.Method, req.URL.RequestURI(), requestBody))
+		if !ok {
+			exchange = Exchange{
+				StatusCode:   http.StatusNotImplemented,
+				ResponseBody: fmt.Sprintf("no recorded exchange for %v %v %v", upstream, req.Method, req.URL.RequestURI()),
+			}
+		}
+		header := exchange.ResponseHeader.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", exchange.StatusCode, http.StatusText(exchange.StatusCode)),
+			StatusCode:    exchange.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(exchange.ResponseBody)),
+			ContentLength: int64(len(exchange.ResponseBody)),
+			Request:       req,
+		}, nil
+	})
+}
+
+func (this *Replayer) next(key string) (result Exchange, ok bool) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	exchanges := this.exchanges[key]
+	if len(exchanges) == 0 {
+		return result, false
+	}
+	if len(exchanges) > 1 {
+		this.exchanges[key] = exchanges[1:]
+	}
+	return exchanges[0], true
+}
+
+// peekRequestBody reads the body of req and replaces it with an unread copy
+func peekRequestBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return string(body), nil
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (this roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return this(req)
+}
@@ -0,0 +1,36 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package upstream
+
+import (
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// newTracingTransport creates a client span for every request attempt and injects the W3C traceparent header,
+// so upstream services can continue the trace of the aggregator request.
+// spans are named by upstream, method and Endpoint (e.g. "iot GET /extended-devices")
+func newTracingTransport(upstream string, next http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(next,
+		otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+			return upstream + " " + req.Method + " " + Endpoint(req.URL.Path)
+		}),
+		otelhttp.WithSpanOptions(trace.WithAttributes(attribute.String("upstream", upstream))),
+	)
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// Warning describes an enrichment step that failed while the response was still delivered (partial response)
+type Warning struct {
+	Upstream string `json:"upstream"`
+	Step     string `json:"step"`
+	Error    string `json:"error"`
+}
+
+const (
+	StepOnlineState   = "online_state"
+	StepLogHistory    = "log_history"
+	StepLogEdge       = "log_edge"
+	StepLogStart      = "log_start"
+	StepAspectNodes   = "aspect_nodes"
+	StepImportTypes   = "import_types"
+	StepFunctions     = "functions"
+	StepDependencies  = "dependencies"
+	StepDeviceClasses = "device_classes"
+	StepHubs          = "hubs"
+	StepHubDevices    = "hub_devices"
+)
+
+type warningsKey struct{}
+
+type warningCollector struct {
+	mux  sync.Mutex
+	list []Warning
+}
+
+// WithWarnings returns a context that collects the warnings of degraded enrichment steps
+func WithWarnings(ctx context.Context) context.Context {
+	return context.WithValue(ctx, warningsKey{}, &warningCollector{})
+}
+
+// GetWarnings returns the warnings collected in ctx
+func GetWarnings(ctx context.Context) []Warning {
+	collector, ok := ctx.Value(warningsKey{}).(*warningCollector)
+	if !ok {
+		return nil
+	}
+	collector.mux.Lock()
+	defer collector.mux.Unlock()
+	return slices.Clone(collector.list)
+}
+
+func AddWarning(ctx context.Context, warning Warning) {
+	collector, ok := ctx.Value(warningsKey{}).(*warningCollector)
+	if !ok {
+		return
+	}
+	collector.mux.Lock()
+	defer collector.mux.Unlock()
+	collector.list = append(collector.list, warning)
+}
+
+// Degrade decides if the failed enrichment step may be skipped.
+// if partial responses are enabled, the error is recorded as Warning and true is returned.
+// returns false if the caller has to fail the request instead.
+func Degrade(ctx context.Context, config Config, upstream string, step string, err error) bool {
+	if !config.PartialResponses || ctx.Err() != nil {
+		return false
+	}
+	slog.WarnContext(ctx, "degraded response", "step", step, "upstream", upstream, "error", err)
+	AddWarning(ctx, Warning{Upstream: upstream, Step: step, Error: err.Error()})
+	return true
+}